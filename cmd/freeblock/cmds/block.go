@@ -2,9 +2,9 @@
 package cmds
 
 import (
-	"fmt"
-	"os"
+	"errors"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -13,20 +13,42 @@ import (
 
 // BlockCmd is a command that blocks domains in the hosts file.
 var BlockCmd = &cobra.Command{
-	Use:   "block DOMAIN [DOMAIN...]",
+	Use:   "block [DOMAIN...]",
 	Short: "block domains",
-	Long: `Block domains by adding a 0.0.0.0 entry to the hosts file for each domain.
+	Long: `Block domains by adding a 0.0.0.0 entry and a :: entry to the hosts file for
+each domain, so that both IPv4 and IPv6 lookups are blocked. Use --ipv6=false to
+only add 0.0.0.0 entries. Other addresses can be used instead of 0.0.0.0 and ::
+with --sink or a #freeblock:sink line; see 'freeblock serve-blockpage --help'.
 
-For hosts already present in the file, the address is set to 0.0.0.0 and the old
-address is kept as a comment at the end of the line. If there is a commented-out
-line for a domain, that line is uncommented.
+For hosts already present in the file, the address is set to 0.0.0.0, or to ::
+for IPv6 addresses, and the old address is kept as a comment at the end of the
+line. If there is a commented-out line for a domain, that line is uncommented.
+
+With --with-subdomains, common subdomains like www.DOMAIN are blocked too. The
+domain and any of its subdomains that aren't in the file yet are added together
+on one line.
+
+With --service, all of the domains of a service like reddit or youtube are
+blocked. The built-in list of services can be changed with a services file, in
+which each line gives a service name followed by its domains. A line replaces
+the built-in service with that name, unless the name starts with '+', in which
+case the domains are added to it.
+
+A domain containing '*', '?' or '[' is a glob pattern, like '*.reddit.com' or
+'*tiktok*'. It blocks every hostname already in the file that matches it, but
+never adds new hostnames. With --regex, each domain is a regular expression
+matched against the whole hostname instead. Use 'freeblock status PATTERN' to
+see which lines a pattern matches.
 `,
-	Args: cobra.MinimumNArgs(1),
+	Args: domainArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := Block(args, hostsFile); err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+		domains, err := withServices(args)
+		if err != nil {
+			exitWithReport(nil, err)
+
+			return
 		}
+		exitWithReport(Block(domains, hostsFile))
 	},
 }
 
@@ -44,92 +66,212 @@ func getDefaultHostsFile() string {
 }
 
 func init() {
-	BlockCmd.Flags().StringVar(
+	addFileFlags(BlockCmd)
+	addSinkFlag(BlockCmd)
+	addOutputFlag(BlockCmd)
+	addSubdomainFlags(BlockCmd)
+	addServiceFlags(BlockCmd)
+	addPatternFlag(BlockCmd)
+	addIPv6Flag(BlockCmd)
+}
+
+// addFileFlags adds the flags for choosing and locking the hosts file to cmd.
+func addFileFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
 		&hostsFile, "hosts-file", defaultHostsFile, "Change the default hosts file.")
+	cmd.Flags().DurationVar(
+		&lockTimeout, "lock-timeout", lockTimeout,
+		"How long to wait for another freeblock process to release the hosts file.")
+	cmd.Flags().BoolVar(
+		&dryRun, "dry-run", false,
+		"Print a diff of the changes instead of writing them, and exit with 2 if there are any.")
 }
 
 const (
 	blockedIP     = "0.0.0.0"
+	blockedIPv6   = "::"
 	commentPrefix = " # "
 )
 
+// blockIPv6 is set unless --ipv6=false is given.
+var blockIPv6 = true
+
+// addIPv6Flag adds the flag for blocking domains with IPv6 entries as well to cmd.
+func addIPv6Flag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&blockIPv6, "ipv6", true,
+		"Add a :: entry for each domain as well as a 0.0.0.0 entry, so that IPv6 lookups are blocked\n"+
+			"too.")
+}
+
+// isIPv6 returns whether ip is an IPv6 address rather than an IPv4 address.
+func isIPv6(ip string) bool {
+	return strings.Contains(ip, ":")
+}
+
 // Block blocks the domains in the hostsFile.
-func Block(domains []string, hostsFile string) error {
-	lines, err := readLines(hostsFile)
-	if err != nil {
-		return err
+func Block(domains []string, hostsFile string) (*Report, error) {
+	var (
+		changes   []change
+		families  [][]string
+		unmatched []string
+	)
+
+	err := editFile(hostsFile, DefaultNower{}, func(f *hosts.File, _ *journal) error {
+		s, err := fileSinks(f)
+		if err != nil {
+			return err
+		}
+		families, unmatched, err = resolveFamilies(f, domains)
+		if err != nil {
+			return err
+		}
+		changes = blockFamilies(f, families, s, blockIPv6)
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrChangesPending) {
+		return newReport(nil, nil), err
+	}
+
+	return newReport(append(flatten(families), unmatched...), changes), err
+}
+
+// blockFile blocks the domains in f, returning the changes made. It's used to block domains again
+// after they were opened, so no IPv6 lines are added that weren't there before.
+func blockFile(f *hosts.File, s sinks, domains []string) []change {
+	families := make([][]string, len(domains))
+	for i, domain := range domains {
+		families[i] = []string{domain}
 	}
 
+	return blockFamilies(f, families, s, false)
+}
+
+// blockFamilies blocks the domains in each family in f, returning the changes made. Domains that
+// aren't in f yet are added with one line for each family, pointing to the IPv4 sink. If ipv6 is
+// set, domains without an IPv6 line get one pointing to the IPv6 sink as well.
+func blockFamilies(f *hosts.File, families [][]string, s sinks, ipv6 bool) []change {
+	var (
+		changes []change
+		domains = flatten(families)
+	)
 	blocked := make(map[string]bool, len(domains))
+	blocked6 := make(map[string]bool, len(domains))
+	directives := make(map[string]string, len(domains))
 
-	// Modify the lines in place.
-	for i, line := range lines {
-		hostnames := line.Hostnames()
-		if len(hostnames) == 0 {
+	// Modify the entries in place.
+	for _, e := range f.Hosts() {
+		// See if this entry refers to one or more of the domains we want to block.
+		if !containsAny(domains, e.Hostnames) {
 			continue
 		}
 
-		// See if this line refers to one or more of the domains we want to block.
-		for _, hostname := range hostnames {
-			if !contains(domains, hostname) {
-				continue
-			}
-
-			line.Uncomment()
-			oldIP := line.GetIP()
-			if oldIP != blockedIP {
-				line.SetIP(blockedIP)
-				line += hosts.Line(commentPrefix + oldIP)
-			}
-			lines[i] = line
+		if c, ok := blockEntry(e, s); ok {
+			changes = append(changes, c)
+		}
 
-			for _, h := range hostnames {
+		for _, h := range e.Hostnames {
+			if isIPv6(e.IP) {
+				blocked6[h] = true
+			} else {
 				blocked[h] = true
 			}
+			if directives[h] == "" {
+				directives[h] = directiveComment(e)
+			}
+		}
+	}
 
-			break
+	// Add entries for sites that haven't been blocked yet.
+	for _, family := range families {
+		changes = appendMissing(f, changes, s.v4, family, blocked, directives)
+		if ipv6 {
+			changes = appendMissing(f, changes, s.v6, family, blocked6, directives)
 		}
 	}
 
-	// Add lines for sites that haven't been blocked yet.
-	for _, domain := range domains {
+	return changes
+}
+
+// maxHostnamesPerLine is the most hostnames that freeblock puts on a line it adds. Windows ignores
+// the hostnames after the ninth on a line.
+const maxHostnamesPerLine = 9
+
+// appendMissing adds lines to f pointing the domains of the family that aren't blocked yet to ip,
+// and marks them as blocked. A domain that already has a line with directives gets the same
+// directives on the new line, so that both lines follow the same schedule. Long families are split
+// over several lines of at most maxHostnamesPerLine hostnames.
+func appendMissing(
+	f *hosts.File, changes []change, ip string, family []string,
+	blocked map[string]bool, directives map[string]string,
+) []change {
+	var comments []string
+	missing := make(map[string][]string)
+
+	for _, domain := range family {
 		if blocked[domain] {
 			continue
 		}
-		lines = append(lines, hosts.Line(blockedIP+" "+domain))
+		blocked[domain] = true
+
+		comment := directives[domain]
+		if _, ok := missing[comment]; !ok {
+			comments = append(comments, comment)
+		}
+		missing[comment] = append(missing[comment], domain)
 	}
 
-	return writeLines(lines, hostsFile)
-}
+	for _, comment := range comments {
+		for domains := missing[comment]; len(domains) > 0; {
+			n := len(domains)
+			if n > maxHostnamesPerLine {
+				n = maxHostnamesPerLine
+			}
 
-func readLines(hostsFile string) ([]hosts.Line, error) {
-	f, err := os.Open(hostsFile)
-	if err != nil {
-		return nil, err
+			e := hosts.NewEntry(ip, domains[:n]...)
+			if comment != "" {
+				e.SetComment(comment)
+			}
+			markBlocked(e)
+			f.Append(e)
+			changes = append(changes, change{Domain: e.Hostnames[0], After: e.Line()})
+			domains = domains[n:]
+		}
 	}
-	lines, err := hosts.ReadLines(f)
-	if err != nil {
-		f.Close()
 
-		return lines, fmt.Errorf("read lines: %w", err)
+	return changes
+}
+
+// directiveComment returns an inline comment holding just the freeblock directives of the entry.
+func directiveComment(e *hosts.Entry) string {
+	ds := e.Directives()
+	for i, d := range ds {
+		ds[i] = hosts.DirectivePrefix + d
 	}
 
-	return lines, f.Close()
+	return strings.Join(ds, " ")
 }
 
-func writeLines(lines []hosts.Line, hostsFile string) error {
-	f, err := os.Create(hostsFile)
-	if err != nil {
-		return fmt.Errorf("write hosts file: %w", err)
-	}
-	err = hosts.WriteLines(f, lines)
-	if err != nil {
-		f.Close()
+// blockEntry blocks the host line, returning the change if there was one. IPv6 lines are pointed to
+// the IPv6 sink and the others to the IPv4 sink, so that each line keeps its own kind of address.
+// The old address is saved in the comment, and a line without one gets the blocked marker if the
+// sink isn't 0.0.0.0 or ::.
+func blockEntry(e *hosts.Entry, s sinks) (change, bool) {
+	before := e.Line()
 
-		return fmt.Errorf("write hosts file: %w", err)
+	e.Disabled = false
+	if sink := s.forIP(e.IP); e.IP != sink {
+		if !s.blocks(e) {
+			e.AppendComment(e.IP)
+		}
+		e.IP = sink
 	}
+	markBlocked(e)
+
+	after := e.Line()
 
-	return f.Close()
+	return change{e.Hostnames[0], before, after}, after != before
 }
 
 func contains(arr []string, v string) bool {
@@ -141,3 +283,13 @@ func contains(arr []string, v string) bool {
 
 	return false
 }
+
+func containsAny(arr, vs []string) bool {
+	for _, v := range vs {
+		if contains(arr, v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -30,6 +31,103 @@ func TestBlockCmd(t *testing.T) {
 	checkWantFile(t, hostsFile)
 }
 
+func TestBlock_crlf(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join("testdata", t.Name())
+
+	// Restore the hosts file once the test is over.
+	backupFile(t, hostsFile)
+
+	// Line endings, the byte order mark, and the lack of a final newline should be kept.
+	if _, err := cmds.Block([]string{"example.com", "google.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the file.
+	checkWantFile(t, hostsFile)
+}
+
+func TestBlock_ipv6(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = `127.0.0.1 localhost
+1.2.3.4 reddit.com
+2606:4700::1 reddit.com
+5.6.7.8 example.com #freeblock:09-17
+`
+	writeString(t, hostsFile, content)
+
+	if _, err := cmds.Block([]string{"reddit.com", "example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each line keeps its own original address, and the new IPv6 line follows the same schedule.
+	want := `127.0.0.1 localhost
+0.0.0.0 reddit.com # 1.2.3.4
+:: reddit.com # 2606:4700::1
+0.0.0.0 example.com #freeblock:09-17 # 5.6.7.8
+:: example.com #freeblock:09-17
+`
+	checkFile(t, hostsFile, want)
+
+	statuses, err := cmds.Status(nil, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.State != cmds.StateBlocked {
+			t.Errorf("expected %s on line %d to be blocked, got %s", s.Domain, s.Line, s.State)
+		}
+	}
+
+	now := time.Date(2021, 10, 4, 20, 0, 0, 0, time.Local)
+	_, err = cmds.Unblock([]string{"reddit.com", "example.com"}, hostsFile, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = `127.0.0.1 localhost
+1.2.3.4 reddit.com
+2606:4700::1 reddit.com
+5.6.7.8 example.com #freeblock:09-17
+#:: example.com #freeblock:09-17
+`
+	checkFile(t, hostsFile, want)
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_noIPv6(t *testing.T) {
+	defer cmds.SetIPv6(false)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n#:: example.com\n")
+
+	if _, err := cmds.Block([]string{"example.com", "google.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing IPv6 lines are still blocked, but no new ones are added.
+	checkFile(t, hostsFile, `127.0.0.1 localhost
+:: example.com
+0.0.0.0 example.com
+0.0.0.0 google.com
+`)
+}
+
+func checkFile(t *testing.T, file, want string) {
+	t.Helper()
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
 func backupFile(t *testing.T, file string) {
 	t.Helper()
 
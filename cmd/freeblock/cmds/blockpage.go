@@ -0,0 +1,325 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// ServeBlockPageCmd is a command that serves a page explaining why a domain is blocked.
+var ServeBlockPageCmd = &cobra.Command{
+	Use:   "serve-blockpage",
+	Short: "serve a page explaining why a domain is blocked",
+	Long: `Serve a web page for blocked domains, showing which domain was blocked, its time
+ranges from the hosts file, and when it can next be unblocked.
+
+For the page to be shown, blocked domains have to resolve to an address that the
+server listens on. Set the address with --sink when blocking, or with a line like
+this at the top of the hosts file:
+
+  #freeblock:sink 127.0.0.1 ::1
+
+Unless --listen is given, the server listens on port 80 of each sink address
+that is a loopback address. Only loopback addresses are allowed. Browsers that
+connect with HTTPS can't be shown the page, because there is no certificate for
+the blocked domain. The server exits on SIGINT or SIGTERM.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		osSignals := make(chan os.Signal, 1)
+		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+		logger := log.New(os.Stderr, "", log.LstdFlags)
+		exitOnError(ServeBlockPage(hostsFile, blockPageAddrs, osSignals, logger))
+	},
+}
+
+var blockPageAddrs []string
+
+func init() {
+	ServeBlockPageCmd.Flags().StringVar(
+		&hostsFile, "hosts-file", defaultHostsFile, "Change the default hosts file.")
+	addSinkFlag(ServeBlockPageCmd)
+	addScheduleFlags(ServeBlockPageCmd)
+	ServeBlockPageCmd.Flags().StringSliceVar(
+		&blockPageAddrs, "listen", nil,
+		"The loopback addresses to listen on, like 127.0.0.1:80. (default port 80 of the sink\n"+
+			"addresses)")
+}
+
+const blockPageReadTimeout = 10 * time.Second
+
+// ServeBlockPage serves BlockPage for the hostsFile on each of the addrs until it receives a signal
+// on osSignals. If addrs is empty, port 80 of each loopback sink address is used.
+func ServeBlockPage(
+	hostsFile string, addrs []string, osSignals <-chan os.Signal, logger *log.Logger,
+) error {
+	if len(addrs) == 0 {
+		var err error
+		addrs, err = sinkAddrs(hostsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	for _, addr := range addrs {
+		if err := checkLoopback(addr); err != nil {
+			closeAll()
+
+			return err
+		}
+
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			closeAll()
+
+			return err
+		}
+		listeners = append(listeners, l)
+		logger.Printf("serving the block page on %s", l.Addr())
+	}
+
+	srv := &http.Server{
+		Handler:           BlockPage(hostsFile, DefaultNower{}),
+		ReadHeaderTimeout: blockPageReadTimeout,
+	}
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- srv.Serve(l)
+		}(l)
+	}
+
+	select {
+	case <-osSignals:
+		logger.Print("stopping")
+
+		return srv.Shutdown(context.Background())
+	case err := <-errs:
+		srv.Close()
+
+		return err
+	}
+}
+
+// sinkAddrs returns port 80 of each sink address of the hostsFile that is a loopback address.
+func sinkAddrs(hostsFile string) ([]string, error) {
+	f, err := readFile(hostsFile)
+	if err != nil {
+		return nil, err
+	}
+	s, err := fileSinks(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, ip := range []string{s.v4, s.v6} {
+		if net.ParseIP(ip).IsLoopback() {
+			addrs = append(addrs, net.JoinHostPort(ip, "80"))
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf(
+			"blocked domains resolve to %s and %s, which aren't loopback addresses; "+
+				"use --sink, a #freeblock:sink line or --listen", s.v4, s.v6)
+	}
+
+	return addrs, nil
+}
+
+// checkLoopback returns an error unless addr is the address and port of a loopback interface.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host != "localhost" && !net.ParseIP(host).IsLoopback() {
+		return fmt.Errorf("%s isn't a loopback address", addr)
+	}
+
+	return nil
+}
+
+// BlockPage returns a handler that serves a page explaining why the domain of the request is
+// blocked by the hostsFile. The hostsFile is read again for each request.
+func BlockPage(hostsFile string, nower Nower) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := readFile(hostsFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		page, err := blockPageFor(f, requestDomain(r), nower.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+		page.HostsFile = hostsFile
+
+		status := http.StatusForbidden
+		if len(page.Lines) == 0 {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = blockPageTemplate.Execute(w, page)
+	})
+}
+
+// requestDomain returns the domain that a request was made to.
+func requestDomain(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// blockPage holds what the block page shows about a domain.
+type blockPage struct {
+	Domain    string
+	HostsFile string
+	// Lines are the lines of the hosts file that block the domain.
+	Lines []blockPageLine
+	// Forbidden is set if the time ranges of any of the lines disallow unblocking the domain.
+	Forbidden bool
+	// NextOpen is when the domain can be unblocked, if it's forbidden now and that's known.
+	NextOpen string
+	// ScheduleError is set if the time ranges can't be read.
+	ScheduleError string
+}
+
+type blockPageLine struct {
+	Num      int
+	Schedule string
+}
+
+// blockPageFor describes how the domain is blocked in f at now.
+func blockPageFor(f *hosts.File, domain string, now time.Time) (*blockPage, error) {
+	page := &blockPage{Domain: domain}
+
+	sink, err := fileSinks(f)
+	if err != nil {
+		return nil, err
+	}
+	schedules, schedulesErr := readSchedules(f)
+	if schedulesErr != nil {
+		page.ScheduleError = schedulesErr.Error()
+	}
+
+	var (
+		open  time.Time
+		never bool
+	)
+	for _, e := range f.Hosts() {
+		if e.Disabled || !sink.blocks(e) || !e.HasHostname(domain) {
+			continue
+		}
+
+		line := blockPageLine{Num: e.Num}
+		if schedules != nil {
+			schedule, lineErr := e.Schedule(schedules.named)
+			if lineErr != nil {
+				page.ScheduleError = lineErr.Error()
+			} else {
+				line.Schedule = scheduleString(schedule)
+			}
+		}
+		page.Lines = append(page.Lines, line)
+
+		if page.ScheduleError != "" {
+			continue
+		}
+		if _, forbidden, _ := schedules.blockingWindow(e, now); !forbidden {
+			continue
+		}
+		page.Forbidden = true
+
+		lineOpen, ok := schedules.nextOpen(e, now)
+		if !ok {
+			never = true
+		} else if lineOpen.After(open) {
+			open = lineOpen
+		}
+	}
+
+	if page.Forbidden && !never {
+		page.NextOpen = scheduleTime(open).Format(nextOpenLayout)
+	}
+
+	return page, nil
+}
+
+const nextOpenLayout = "Monday, January 2 at 15:04 MST"
+
+// scheduleString describes the windows of the schedule.
+func scheduleString(s hosts.Schedule) string {
+	windows := make([]string, len(s.Windows))
+	for i, w := range s.Windows {
+		windows[i] = w.String()
+	}
+
+	return strings.Join(windows, ", ")
+}
+
+var blockPageTemplate = template.Must(template.New("blockpage").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Domain}} {{if .Lines}}is{{else}}isn't{{end}} blocked</title>
+<style>
+body {
+	font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #333;
+}
+code { background: #eee; padding: 0 0.2em; }
+</style>
+</head>
+<body>
+{{if .Lines -}}
+<h1>{{.Domain}} is blocked</h1>
+<p>It's blocked by the hosts file <code>{{.HostsFile}}</code>:</p>
+<ul>
+{{range .Lines}}<li>line {{.Num}}:
+{{- if .Schedule}} blocked during {{.Schedule}}{{else}} no time ranges{{end}}</li>
+{{end -}}
+</ul>
+{{if .ScheduleError -}}
+<p>The time ranges can't be read: {{.ScheduleError}}</p>
+{{- else if .NextOpen -}}
+<p>It can be unblocked on {{.NextOpen}}.</p>
+{{- else if .Forbidden -}}
+<p>Its time ranges don't allow unblocking it any time soon.</p>
+{{- else -}}
+<p>It can be unblocked now with <code>freeblock unblock {{.Domain}}</code>.</p>
+{{- end}}
+{{- else -}}
+<h1>{{.Domain}} isn't blocked</h1>
+<p>The hosts file <code>{{.HostsFile}}</code> doesn't block {{.Domain}}.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+package cmds_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestBlockPage(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.1 reddit.com #freeblock:mon-fri@09-12,12-17 # 1.2.3.4
+::1 reddit.com #freeblock:mon-fri@09-12,12-17
+0.0.0.0 example.com
+#127.0.0.1 github.com
+`)
+
+	// This is a Monday.
+	now := time.Date(2021, 10, 4, 10, 30, 0, 0, time.UTC)
+	handler := cmds.BlockPage(hostsFile, MockNower{now})
+
+	tests := map[string]struct {
+		host   string
+		status int
+		want   []string
+	}{
+		"forbidden": {"reddit.com:80", http.StatusForbidden, []string{
+			"<title>reddit.com is blocked</title>",
+			"line 3: blocked during mon-fri@09:00-12:00, mon-fri@12:00-17:00",
+			"line 4: blocked during",
+			"It can be unblocked on Monday, October 4 at 17:00 UTC.",
+		}},
+		"no_schedule": {"Example.com.", http.StatusForbidden, []string{
+			"line 5: no time ranges",
+			"It can be unblocked now with <code>freeblock unblock example.com</code>.",
+		}},
+		"loopback": {"localhost", http.StatusNotFound, []string{
+			"<h1>localhost isn't blocked</h1>",
+		}},
+		"not_blocked": {"github.com", http.StatusNotFound, []string{
+			"<title>github.com isn't blocked</title>",
+			"<h1>github.com isn't blocked</h1>",
+		}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/some/page", nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rec.Code)
+			}
+			body := rec.Body.String()
+			for _, want := range tc.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected page to contain %q:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockPage_badHostsFile(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "#freeblock:sink 127.0.0.1 127.0.0.2\n0.0.0.0 reddit.com\n")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "reddit.com"
+	rec := httptest.NewRecorder()
+	cmds.BlockPage(hostsFile, MockNower{time.Now()}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestServeBlockPage_notLoopback(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "0.0.0.0 reddit.com\n")
+	logger := log.New(io.Discard, "", 0)
+
+	// The default sinks aren't loopback addresses, so there's nothing to listen on.
+	if err := cmds.ServeBlockPage(hostsFile, nil, nil, logger); err == nil {
+		t.Error("expected an error for the default sinks")
+	}
+
+	err := cmds.ServeBlockPage(hostsFile, []string{"127.0.0.1:0", "0.0.0.0:0"}, nil, logger)
+	if err == nil || !strings.Contains(err.Error(), "isn't a loopback address") {
+		t.Errorf("expected an error for a non-loopback address, got %v", err)
+	}
+}
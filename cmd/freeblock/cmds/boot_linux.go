@@ -0,0 +1,34 @@
+package cmds
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemBootTime returns when the system was booted, read from the btime line of /proc/stat.
+func systemBootTime() (time.Time, bool) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 || fields[0] != "btime" {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		return time.Unix(sec, 0), true
+	}
+
+	return time.Time{}, false
+}
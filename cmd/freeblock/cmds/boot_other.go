@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package cmds
+
+import "time"
+
+// systemBootTime reports that the boot time is unknown on this platform.
+func systemBootTime() (time.Time, bool) {
+	return time.Time{}, false
+}
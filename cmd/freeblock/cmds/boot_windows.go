@@ -0,0 +1,16 @@
+package cmds
+
+import "time"
+
+var procGetTickCount64 = kernel32.NewProc("GetTickCount64")
+
+// systemBootTime returns when the system was booted, worked out from the number of milliseconds
+// since boot.
+func systemBootTime() (time.Time, bool) {
+	if procGetTickCount64.Find() != nil {
+		return time.Time{}, false
+	}
+	ms, _, _ := procGetTickCount64.Call()
+
+	return time.Now().Add(-time.Duration(ms) * time.Millisecond).Truncate(time.Second), true
+}
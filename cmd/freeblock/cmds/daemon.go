@@ -0,0 +1,156 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// DaemonCmd is a command that keeps running and enforces the schedules in the hosts file.
+var DaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "keep running and enforce time ranges as they start and end",
+	Long: `Keep running and enforce the #freeblock: time ranges in the hosts file, like
+running 'enforce' at the exact moment each time range starts or ends.
+
+The daemon sleeps until the next time range starts or ends. It also checks the
+hosts file for changes every --poll-interval and enforces the time ranges again
+when it changes, so new directives and domains unblocked by hand are picked up.
+Changes are logged to stderr. The daemon exits on SIGINT or SIGTERM.
+
+With --unblock, domains are also unblocked when their time ranges end.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		osSignals := make(chan os.Signal, 1)
+		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+		logger := log.New(os.Stderr, "", log.LstdFlags)
+		exitOnError(Daemon(
+			hostsFile, enforceUnblock, daemonPollInterval, osSignals, DefaultClock{}, logger))
+	},
+}
+
+var daemonPollInterval = 10 * time.Second
+
+func init() {
+	addFileFlags(DaemonCmd)
+	addSinkFlag(DaemonCmd)
+	addScheduleFlags(DaemonCmd)
+	DaemonCmd.Flags().BoolVar(
+		&enforceUnblock, "unblock", false, "Also unblock domains when their time ranges end.")
+	DaemonCmd.Flags().DurationVar(
+		&daemonPollInterval, "poll-interval", daemonPollInterval,
+		"How often to check the hosts file for changes.")
+}
+
+// Daemon enforces the schedules in the hostsFile until it receives a signal on osSignals. The
+// schedules are enforced when Daemon starts, whenever a window starts or ends, and whenever the
+// hostsFile has changed since the last check. The hostsFile is checked for changes every poll.
+// Changes and errors are logged to logger.
+func Daemon(
+	hostsFile string, unblock bool, poll time.Duration, osSignals <-chan os.Signal, clock Clock,
+	logger *log.Logger,
+) error {
+	if dryRun {
+		return errors.New("the daemon can't run with --dry-run")
+	}
+	if poll <= 0 {
+		return fmt.Errorf("invalid poll interval %v", poll)
+	}
+
+	var (
+		last    os.FileInfo
+		next    time.Time
+		hasNext bool
+	)
+
+	logger.Printf("enforcing schedules in %s", hostsFile)
+
+	for {
+		info, statErr := os.Stat(hostsFile)
+		if statErr != nil {
+			logger.Print(statErr)
+		}
+
+		now := clock.Now()
+		if last == nil || info == nil || fileChanged(last, info) || hasNext && !now.Before(next) {
+			next, hasNext = daemonEnforce(hostsFile, unblock, clock, logger)
+
+			// Don't count our own changes as changes to the file.
+			info, statErr = os.Stat(hostsFile)
+			if statErr != nil {
+				logger.Print(statErr)
+			}
+		}
+		last = info
+
+		wait := poll
+		if hasNext && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+
+		select {
+		case <-osSignals:
+			logger.Print("stopping")
+
+			return nil
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// daemonEnforce enforces the schedules in the hostsFile once, logging the changes. It returns the
+// next time that a window starts or ends.
+func daemonEnforce(
+	hostsFile string, unblock bool, nower Nower, logger *log.Logger,
+) (next time.Time, ok bool) {
+	changes, err := enforce(hostsFile, unblock, nower)
+	if err != nil {
+		logger.Print(err)
+	}
+
+	f, readErr := readFile(hostsFile)
+	s := defaultSinks
+	if readErr == nil {
+		s = fileSinksOrDefault(f)
+	}
+	for _, c := range changes {
+		logger.Printf("%s %s: %s", changeVerb(c, s), c.Domain, c.After)
+	}
+	if readErr != nil {
+		logger.Print(readErr)
+
+		return time.Time{}, false
+	}
+	schedules, err := readSchedules(f)
+	if err != nil {
+		// The error was already logged by enforce.
+		return time.Time{}, false
+	}
+
+	return schedules.nextChange(f, nower.Now())
+}
+
+// changeVerb describes what a change did to its line.
+func changeVerb(c change, s sinks) string {
+	e := hosts.ParseLine(c.After)
+	if s.blocks(e) && !e.Disabled {
+		return "blocked"
+	}
+
+	return "unblocked"
+}
+
+// fileChanged returns whether a file seems to have changed between two calls to os.Stat.
+func fileChanged(a, b os.FileInfo) bool {
+	return !a.ModTime().Equal(b.ModTime()) || a.Size() != b.Size() || !os.SameFile(a, b)
+}
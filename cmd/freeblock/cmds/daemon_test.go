@@ -0,0 +1,109 @@
+package cmds_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestDaemon(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "1.2.3.4 reddit.com #freeblock:09-17\n")
+
+	// October 4, 2021 was a Monday.
+	clock := &daemonClock{
+		MockClock: MockClock{T: time.Date(2021, 10, 4, 8, 0, 0, 0, time.Local)},
+		stop:      time.Date(2021, 10, 4, 17, 0, 0, 0, time.Local),
+		signals:   make(chan os.Signal, 1),
+	}
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	err := cmds.Daemon(hostsFile, true, 24*time.Hour, clock.signals, clock, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The daemon should sleep until each transition.
+	want := []time.Duration{time.Hour, 8 * time.Hour, 16 * time.Hour}
+	if diff := cmp.Diff(want, clock.waits); diff != "" {
+		t.Error("unexpected waits (-want +got):\n" + diff)
+	}
+
+	wantLogs := "enforcing schedules in " + hostsFile + `
+blocked reddit.com: 0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+unblocked reddit.com: 1.2.3.4 reddit.com #freeblock:09-17
+stopping
+`
+	if diff := cmp.Diff(wantLogs, logs.String()); diff != "" {
+		t.Error("unexpected logs (-want +got):\n" + diff)
+	}
+}
+
+func TestDaemon_reload(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "1.2.3.4 reddit.com\n")
+
+	start := time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local)
+	clock := &daemonClock{
+		MockClock: MockClock{T: start},
+		stop:      start.Add(2 * time.Minute),
+		signals:   make(chan os.Signal, 1),
+	}
+
+	// Add a directive to the file while the daemon is waiting.
+	clock.OnAfter = func(time.Duration) {
+		if clock.T.Equal(start) {
+			writeString(t, hostsFile, "1.2.3.4 reddit.com #freeblock:09-17\n")
+		}
+	}
+
+	var logs bytes.Buffer
+	err := cmds.Daemon(hostsFile, false, time.Minute, clock.signals, clock, log.New(&logs, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+// daemonClock is a MockClock that sends a signal instead of waiting once it reaches stop, so that
+// the daemon stops there.
+type daemonClock struct {
+	MockClock
+
+	stop    time.Time
+	signals chan os.Signal
+	waits   []time.Duration
+}
+
+func (c *daemonClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	if !c.T.Before(c.stop) {
+		c.signals <- syscall.SIGTERM
+
+		return nil
+	}
+
+	return c.MockClock.After(d)
+}
@@ -0,0 +1,313 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// DiffCmd is a command that shows what another command would change in the hosts file.
+var DiffCmd = &cobra.Command{
+	Use:   "diff COMMAND [ARG...]",
+	Short: "show what a command would change without changing anything",
+	Long: `Run another freeblock command in memory, and print a unified diff of the changes
+it would make to the hosts file. This is the same as running the command with
+--dry-run. For example:
+
+  freeblock diff block reddit.com
+
+The exit code is 2 if there are changes pending, and 0 otherwise. With --output
+json, the diff is printed to stderr instead of stdout.
+`,
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] == "-h" || args[0] == "--help" {
+			_ = cmd.Help()
+
+			return
+		}
+
+		sub, err := findDiffable(cmd, args)
+		if err != nil {
+			exitOnError(err)
+		}
+		if help, _ := sub.Flags().GetBool("help"); help {
+			_ = sub.Help()
+
+			return
+		}
+
+		dryRun = true
+		sub.Run(sub, sub.Flags().Args())
+	},
+}
+
+// findDiffable finds the command given to diff in args and parses its flags. Only the commands that
+// edit the hosts file can be run by diff, because the others would just run for real.
+func findDiffable(cmd *cobra.Command, args []string) (*cobra.Command, error) {
+	sub, subArgs, err := cmd.Root().Find(args)
+	if err != nil {
+		return nil, err
+	}
+	if !diffable(sub) {
+		return nil, fmt.Errorf("%q doesn't edit the hosts file, so it can't be run by diff", sub.Name())
+	}
+
+	sub.InitDefaultHelpFlag()
+	if err = sub.ParseFlags(subArgs); err != nil {
+		return nil, err
+	}
+	if help, _ := sub.Flags().GetBool("help"); help {
+		return sub, nil
+	}
+	if err = sub.ValidateArgs(sub.Flags().Args()); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// diffable returns whether diff can run the command.
+func diffable(sub *cobra.Command) bool {
+	switch sub {
+	case BlockCmd, UnblockCmd, OpenCmd, EnforceCmd, RecoverCmd, VacationCmd:
+		return sub.Run != nil
+	}
+
+	return false
+}
+
+// dryRun is set if changes to the hosts file should be printed as a diff instead of written.
+var dryRun bool
+
+// ErrChangesPending is returned in dry-run mode if the command would have changed the hosts file.
+var ErrChangesPending = errors.New("changes pending")
+
+// diffContext is the number of unchanged lines shown around each change in a diff.
+const diffContext = 3
+
+// unifiedDiff returns a unified diff between the lines a and b, or the empty string if they are the
+// same.
+func unifiedDiff(name string, a, b []string) string {
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+
+	for i := 0; i < len(ops); {
+		// Find the next change.
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// Extend the hunk until there are enough unchanged lines to end it.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+		}
+		writeHunk(&out, ops[start:end])
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	var aLen, bLen int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+
+	// Line numbers start at 1, except that an empty range starts at the line before it.
+	aStart, bStart := ops[0].a, ops[0].b
+	if aLen != 0 {
+		aStart++
+	}
+	if bLen != 0 {
+		bStart++
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+	for _, op := range ops {
+		fmt.Fprintf(out, "%c%s\n", op.kind, op.text)
+	}
+}
+
+// diffOp is a single line of a diff. The kind is ' ' for an unchanged line, '-' for a removed line,
+// or '+' for an added line. a and b are the indices in the old and new lines where the op happens.
+type diffOp struct {
+	kind byte
+	text string
+	a, b int
+}
+
+// diffLines finds the shortest edit script from a to b, using the linear space variant of the
+// algorithm described in "An O(ND) Difference Algorithm and Its Variations" by Eugene W. Myers.
+func diffLines(a, b []string) []diffOp {
+	d := differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+
+	return d.ops
+}
+
+// differ collects the ops of a diff between a and b in order.
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// compare adds the ops turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Lines at the start and end that are the same don't need to be searched.
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, diffOp{' ', d.a[aLo], aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd:
+		for y := bLo; y < bEnd; y++ {
+			d.ops = append(d.ops, diffOp{'+', d.b[y], aLo, y})
+		}
+	case bLo == bEnd:
+		for x := aLo; x < aEnd; x++ {
+			d.ops = append(d.ops, diffOp{'-', d.a[x], x, bLo})
+		}
+	default:
+		x, y := d.split(aLo, aEnd, bLo, bEnd)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aEnd, y, bEnd)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{' ', d.a[aEnd+i], aEnd + i, bEnd + i})
+	}
+}
+
+// split finds a point on a shortest edit script from a[aLo:aHi] to b[bLo:bHi] by searching forwards
+// from the start and backwards from the end at the same time until the searches meet. Both ranges
+// must be non-empty.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (x, y int) {
+	a, b := d.a[aLo:aHi], d.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// vf[offset+k] is the furthest x reached forwards on diagonal k, and vb[offset+k] is the
+	// furthest distance reached backwards from the end on diagonal k. -1 means not reached yet.
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	// If delta is odd, the forward search is the one that finds the overlap.
+	front := delta%2 != 0
+	// Diagonals that ran off the edge of the grid are skipped in later rounds.
+	var fStart, fEnd, bStart, bEnd int
+
+	for dist := 0; dist < maxD; dist++ {
+		for k := -dist + fStart; k <= dist-fEnd; k += 2 {
+			var x1 int
+			if k == -dist || (k != dist && vf[offset+k-1] < vf[offset+k+1]) {
+				x1 = vf[offset+k+1] // move down
+			} else {
+				x1 = vf[offset+k-1] + 1 // move right
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			vf[offset+k] = x1
+
+			switch {
+			case x1 > n:
+				fEnd += 2
+			case y1 > m:
+				fStart += 2
+			case front:
+				i := offset + delta - k
+				if i >= 0 && i < len(vb) && vb[i] != -1 && x1 >= n-vb[i] {
+					return aLo + x1, bLo + y1
+				}
+			}
+		}
+
+		for k := -dist + bStart; k <= dist-bEnd; k += 2 {
+			var x2 int
+			if k == -dist || (k != dist && vb[offset+k-1] < vb[offset+k+1]) {
+				x2 = vb[offset+k+1]
+			} else {
+				x2 = vb[offset+k-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			vb[offset+k] = x2
+
+			switch {
+			case x2 > n:
+				bEnd += 2
+			case y2 > m:
+				bStart += 2
+			case !front:
+				i := offset + delta - k
+				if i >= 0 && i < len(vf) && vf[i] != -1 && vf[i] >= n-x2 {
+					x1 := vf[i]
+
+					return aLo + x1, bLo + x1 - (i - offset)
+				}
+			}
+		}
+	}
+
+	// The searches always meet, but if they didn't, removing all of a and adding all of b is
+	// still a correct edit script.
+	return aHi, bLo
+}
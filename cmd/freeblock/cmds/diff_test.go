@@ -0,0 +1,172 @@
+package cmds_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	// Twenty lines, "1" to "20".
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+
+	tests := map[string]struct {
+		a, b []string
+		want string
+	}{
+		"same":  {lines, lines, ""},
+		"empty": {nil, nil, ""},
+		"from_empty": {nil, []string{"a", "b"}, `--- hosts
++++ hosts
+@@ -0,0 +1,2 @@
++a
++b
+`},
+		"append": {lines, append(append([]string(nil), lines...), "21"), `--- hosts
++++ hosts
+@@ -18,3 +18,4 @@
+ 18
+ 19
+ 20
++21
+`},
+		"two_hunks": {
+			lines,
+			[]string{
+				"1", "two", "3", "4", "5", "6", "7", "8", "9", "10",
+				"11", "12", "13", "14", "15", "16", "17", "18", "20",
+			},
+			`--- hosts
++++ hosts
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -16,5 +16,4 @@
+ 16
+ 17
+ 18
+-19
+ 20
+`,
+		},
+		"merged_hunks": {
+			lines[:10],
+			[]string{"1", "two", "3", "4", "5", "6", "7", "8", "nine", "10"},
+			`--- hosts
++++ hosts
+@@ -1,10 +1,10 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++nine
+ 10
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := cmds.UnifiedDiff("hosts", tc.a, tc.b)
+
+			diff := cmp.Diff(tc.want, got)
+			if diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_dryRun(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = "127.0.0.1 localhost\n0.0.0.0 google.com\n:: google.com\n"
+	writeString(t, hostsFile, content)
+
+	defer cmds.SetDryRun(true)()
+
+	// There's nothing to change for a domain that's already blocked.
+	if _, err := cmds.Block([]string{"google.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := cmds.Block([]string{"example.com"}, hostsFile)
+	if !errors.Is(err, cmds.ErrChangesPending) {
+		t.Fatalf("expected ErrChangesPending, got %v", err)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(content, string(got)); diff != "" {
+		t.Error("hosts file changed in dry-run mode (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestFindDiffable(t *testing.T) {
+	root := &cobra.Command{Use: "freeblock"}
+	subs := []*cobra.Command{
+		cmds.BlockCmd, cmds.DaemonCmd, cmds.DiffCmd, cmds.ExitCodesHelp, cmds.ServeBlockPageCmd,
+	}
+	root.AddCommand(subs...)
+	defer root.RemoveCommand(subs...)
+
+	tests := map[string]struct {
+		args    []string
+		want    *cobra.Command
+		wantErr bool
+	}{
+		"block":        {[]string{"block", "reddit.com"}, cmds.BlockCmd, false},
+		"block_help":   {[]string{"block", "--help"}, cmds.BlockCmd, false},
+		"help_topic":   {[]string{"exit-codes"}, nil, true},
+		"daemon":       {[]string{"daemon"}, nil, true},
+		"serve":        {[]string{"serve-blockpage"}, nil, true},
+		"diff":         {[]string{"diff", "block", "reddit.com"}, nil, true},
+		"unknown":      {[]string{"frobnicate"}, nil, true},
+		"unknown_flag": {[]string{"block", "--frobnicate", "reddit.com"}, nil, true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := cmds.FindDiffable(cmds.DiffCmd, tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	// Don't leave --help set for the other tests.
+	if err := cmds.BlockCmd.Flags().Set("help", "false"); err != nil {
+		t.Fatal(err)
+	}
+}
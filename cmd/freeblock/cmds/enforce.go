@@ -0,0 +1,142 @@
+package cmds
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// EnforceCmd is a command that blocks domains whose time ranges apply right now.
+var EnforceCmd = &cobra.Command{
+	Use:   "enforce",
+	Short: "block domains whose time ranges apply right now",
+	Long: `Block every domain whose #freeblock: time range applies right now, even if
+it was unblocked before the time range started. This is safe to run as often as
+you like, for example from cron every minute:
+
+  * * * * * freeblock enforce
+
+With --unblock, blocked domains with time ranges that don't apply right now are
+unblocked as well, so that those domains are blocked exactly during their time
+ranges. Lines without #freeblock: directives are never changed.
+
+Lines whose directives can't be parsed are left alone, and the exit code is 5
+once the other lines have been enforced.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitWithReport(Enforce(hostsFile, enforceUnblock, DefaultNower{}))
+	},
+}
+
+var enforceUnblock bool
+
+func init() {
+	addFileFlags(EnforceCmd)
+	addSinkFlag(EnforceCmd)
+	addOutputFlag(EnforceCmd)
+	addScheduleFlags(EnforceCmd)
+	EnforceCmd.Flags().BoolVar(
+		&enforceUnblock, "unblock", false,
+		"Also unblock domains whose time ranges don't apply right now.")
+}
+
+// Enforce blocks the domains in the hostsFile whose schedules apply at the current time. If unblock
+// is set, blocked domains whose schedules don't apply are unblocked.
+func Enforce(hostsFile string, unblock bool, nower Nower) (*Report, error) {
+	changes, err := enforce(hostsFile, unblock, nower)
+	if err != nil && !errors.Is(err, ErrChangesPending) {
+		return newReport(nil, nil), err
+	}
+
+	return newReport(changedDomains(changes), changes), err
+}
+
+// enforce runs enforceFile on the hostsFile. If some lines have malformed directives, the changes
+// to the other lines are written and returned along with the error.
+func enforce(hostsFile string, unblock bool, nower Nower) ([]change, error) {
+	var (
+		changes []change
+		badLine error
+	)
+
+	err := editFile(hostsFile, nower, func(f *hosts.File, _ *journal) error {
+		var err error
+		changes, badLine, err = enforceFile(f, unblock, nower.Now())
+
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrChangesPending) {
+		return nil, err
+	}
+	if err == nil {
+		err = badLine
+	}
+
+	return changes, err
+}
+
+// enforceFile blocks the lines of f whose schedules apply at now, and if unblock is set, unblocks
+// the blocked lines whose schedules don't. Lines with malformed directives are skipped, and the
+// first of their errors is returned as badLine.
+func enforceFile(
+	f *hosts.File, unblock bool, now time.Time,
+) (changes []change, badLine, err error) {
+	schedules, err := readSchedules(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := fileSinks(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, e := range f.Hosts() {
+		if len(e.Directives()) == 0 {
+			continue
+		}
+
+		_, forbidden, lineErr := schedules.blockingWindow(e, now)
+		if lineErr != nil {
+			if badLine == nil {
+				badLine = lineErr
+			}
+
+			continue
+		}
+
+		var (
+			c       change
+			changed bool
+		)
+		switch {
+		case forbidden:
+			c, changed = blockEntry(e, s)
+		case unblock && !e.Disabled:
+			c, changed = unblockEntry(e, s)
+		}
+		if changed {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, badLine, nil
+}
+
+// changedDomains returns the hostnames of the lines changed by changes.
+func changedDomains(changes []change) []string {
+	var out []string
+
+	for _, c := range changes {
+		for _, h := range hosts.ParseLine(c.After).Hostnames {
+			if !contains(out, h) {
+				out = append(out, h)
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,126 @@
+package cmds_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+func TestEnforce(t *testing.T) {
+	t.Parallel()
+
+	const content = `127.0.0.1 localhost
+1.2.3.4   reddit.com #freeblock:09-17
+#0.0.0.0  youtube.com #freeblock:09-17
+0.0.0.0   example.com #freeblock:22-06 # 5.6.7.8
+0.0.0.0   google.com
+1.1.1.1   github.com
+`
+
+	tests := map[string]struct {
+		unblock     bool
+		now         time.Time
+		want        string
+		wantChanged []string
+	}{
+		"during": {
+			now: time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local),
+			want: `127.0.0.1 localhost
+0.0.0.0   reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0  youtube.com #freeblock:09-17
+0.0.0.0   example.com #freeblock:22-06 # 5.6.7.8
+0.0.0.0   google.com
+1.1.1.1   github.com
+`,
+			wantChanged: []string{"reddit.com", "youtube.com"},
+		},
+		"during_unblock": {
+			unblock: true,
+			now:     time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local),
+			want: `127.0.0.1 localhost
+0.0.0.0   reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0  youtube.com #freeblock:09-17
+5.6.7.8   example.com #freeblock:22-06
+0.0.0.0   google.com
+1.1.1.1   github.com
+`,
+			wantChanged: []string{"reddit.com", "youtube.com", "example.com"},
+		},
+		"after": {
+			now:         time.Date(2021, 10, 4, 18, 0, 0, 0, time.Local),
+			want:        content,
+			wantChanged: []string{},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hostsFile := filepath.Join(t.TempDir(), "hosts")
+			writeString(t, hostsFile, content)
+
+			r, err := cmds.Enforce(hostsFile, tc.unblock, MockNower{tc.now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantChanged, r.Changed); diff != "" {
+				t.Error("unexpected changed domains (-want +got):\n" + diff)
+			}
+
+			got, err := os.ReadFile(hostsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Error("unexpected hosts file (-want +got):\n" + diff)
+			}
+
+			// Running it again changes nothing.
+			r, err = cmds.Enforce(hostsFile, tc.unblock, MockNower{tc.now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Changed) != 0 {
+				t.Errorf("expected no changes the second time, got %v", r.Changed)
+			}
+		})
+	}
+}
+
+func TestEnforce_badDirective(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `1.2.3.4 reddit.com #freeblock:9to5
+1.2.3.4 youtube.com #freeblock:09-17
+`)
+
+	now := time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local)
+
+	_, err := cmds.Enforce(hostsFile, false, MockNower{now})
+	var as *hosts.ParseError
+	if !errors.As(err, &as) || as.Line != 1 {
+		t.Fatalf("expected *hosts.ParseError for line 1, got %v", err)
+	}
+
+	// The other line is still enforced.
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `1.2.3.4 reddit.com #freeblock:9to5
+0.0.0.0 youtube.com #freeblock:09-17 # 1.2.3.4
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
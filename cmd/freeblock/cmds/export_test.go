@@ -0,0 +1,120 @@
+package cmds
+
+import "time"
+
+// LockFile exposes lockFile for testing.
+var LockFile = lockFile
+
+// SetLockTimeout sets the lock timeout used by the commands, returning a function that restores the
+// old value.
+func SetLockTimeout(d time.Duration) (restore func()) {
+	old := lockTimeout
+	lockTimeout = d
+
+	return func() { lockTimeout = old }
+}
+
+// UnifiedDiff exposes unifiedDiff for testing.
+var UnifiedDiff = unifiedDiff
+
+// SetDryRun sets dry-run mode, returning a function that restores the old value.
+func SetDryRun(v bool) (restore func()) {
+	old := dryRun
+	dryRun = v
+
+	return func() { dryRun = old }
+}
+
+// ExitCode exposes exitCode for testing.
+var ExitCode = exitCode
+
+// SetTimezone sets the default time zone for schedules as if it were given with --timezone,
+// returning a function that restores the old value.
+func SetTimezone(name string) (restore func(), err error) {
+	old := location
+
+	return func() { location = old }, locationValue{}.Set(name)
+}
+
+// SetPartial sets partial mode, returning a function that restores the old value.
+func SetPartial(v bool) (restore func()) {
+	old := partial
+	partial = v
+
+	return func() { partial = old }
+}
+
+// SetSubdomains sets the subdomains included by --with-subdomains and turns it on, returning a
+// function that restores the old values.
+func SetSubdomains(subs ...string) (restore func()) {
+	oldWith, oldSubs := withSubdomains, subdomains
+	withSubdomains, subdomains = true, subs
+
+	return func() { withSubdomains, subdomains = oldWith, oldSubs }
+}
+
+// SetServices sets the services given with --service and the services file, returning a function
+// that restores the old values.
+func SetServices(file string, names ...string) (restore func()) {
+	oldFile, oldNames := servicesFile, serviceNames
+	servicesFile, serviceNames = file, names
+
+	return func() { servicesFile, serviceNames = oldFile, oldNames }
+}
+
+// WithServices exposes withServices for testing.
+var WithServices = withServices
+
+// SetRegex sets regular expression mode, returning a function that restores the old value.
+func SetRegex(v bool) (restore func()) {
+	old := useRegex
+	useRegex = v
+
+	return func() { useRegex = old }
+}
+
+// SetIPv6 sets whether Block adds IPv6 lines, returning a function that restores the old value.
+func SetIPv6(v bool) (restore func()) {
+	old := blockIPv6
+	blockIPv6 = v
+
+	return func() { blockIPv6 = old }
+}
+
+// SetSink sets the sink addresses as if they were given with --sink, returning a function that
+// restores the old value.
+func SetSink(s string) (restore func()) {
+	old := sinkFlag
+	sinkFlag = s
+
+	return func() { sinkFlag = old }
+}
+
+// SetRename replaces the function used to rename files over the hosts file, returning a function
+// that restores the old one.
+func SetRename(f func(oldpath, newpath string) error) (restore func()) {
+	old := rename
+	rename = f
+
+	return func() { rename = old }
+}
+
+// SetBootTime sets the time the system appears to have booted, returning a function that restores
+// the old value.
+func SetBootTime(boot time.Time) (restore func()) {
+	old := bootTime
+	bootTime = func() (time.Time, bool) { return boot, true }
+
+	return func() { bootTime = old }
+}
+
+// FindDiffable exposes findDiffable for testing.
+var FindDiffable = findDiffable
+
+// SetOutput sets the output format as if it were given with --output, returning a function that
+// restores the old value.
+func SetOutput(format string) (restore func(), err error) {
+	old := output
+
+	return func() { output = old }, outputValue{}.Set(format)
+}
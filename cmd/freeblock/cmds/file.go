@@ -0,0 +1,247 @@
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// editFile reads the hosts file, passes it to edit, and writes back the result, all while holding
+// the lock on the hosts file. Before edit is called, any changes left behind by abandoned 'open'
+// sessions are reverted. The journal passed to edit is written along with the hosts file. If edit
+// returns an error, nothing is written, and the hosts file isn't written if it didn't change.
+//
+// In dry-run mode, a diff of the changes is printed to stdout instead of being written, and
+// ErrChangesPending is returned if there are any.
+func editFile(hostsFile string, nower Nower, edit func(f *hosts.File, j *journal) error) error {
+	return withLock(hostsFile, func() error {
+		f, err := readFile(hostsFile)
+		if err != nil {
+			return err
+		}
+		j, err := readJournal(hostsFile)
+		if err != nil {
+			return err
+		}
+		before := f.Lines()
+
+		j.recover(f, nower.Now())
+
+		err = edit(f, j)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			diff := unifiedDiff(hostsFile, lineStrings(before), lineStrings(f.Lines()))
+			if diff == "" {
+				return nil
+			}
+			if output == outputJSON {
+				// Keep stdout for the JSON report.
+				fmt.Fprint(os.Stderr, diff)
+			} else {
+				fmt.Print(diff)
+			}
+
+			return ErrChangesPending
+		}
+
+		// New sessions need to be in the journal before the hosts file changes, or a crash in
+		// between would leave changes that nobody knows to revert. Recovered sessions stay in the
+		// journal until their changes have been reverted in the hosts file.
+		if j.added {
+			err = j.write(append(j.Sessions, j.recovered...))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Leave the hosts file alone if nothing changed, so that its inode and modification time
+		// only change when its content does.
+		if !equalLines(before, f.Lines()) {
+			err = writeFile(f, hostsFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		if j.dirty {
+			return j.write(j.Sessions)
+		}
+
+		return nil
+	})
+}
+
+func equalLines(a, b []hosts.Line) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func lineStrings(lines []hosts.Line) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = string(l)
+	}
+
+	return out
+}
+
+func readFile(hostsFile string) (*hosts.File, error) {
+	r, err := os.Open(hostsFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := hosts.Read(r)
+	if err != nil {
+		r.Close()
+
+		return nil, fmt.Errorf("read hosts file: %w", err)
+	}
+
+	return f, r.Close()
+}
+
+// writeFile replaces the hosts file with f. If hostsFile is a symlink, the file it points to is
+// replaced and the link is left alone.
+func writeFile(f *hosts.File, hostsFile string) error {
+	target, err := filepath.EvalSymlinks(hostsFile)
+	if err != nil {
+		return fmt.Errorf("write hosts file: %w", err)
+	}
+
+	err = replaceFile(target, f.Write)
+	if err != nil {
+		return fmt.Errorf("write hosts file: %w", err)
+	}
+
+	return nil
+}
+
+// replaceFile replaces the file at path with the output of write. The new content is written to a
+// temporary file in the same directory, synced, and then renamed over the old file, so that a
+// failure at any point leaves the old content in place. The mode and owner of the old file are
+// kept. If there is no old file, the new one is created with mode 0644.
+//
+// Some files can't be replaced this way, like a hosts file bind-mounted into a container. Those are
+// overwritten in place instead, with a warning, since a crash during the write could leave the file
+// truncated.
+func replaceFile(path string, write func(w io.Writer) error) error {
+	info, err := os.Stat(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = renameOver(path, info, write)
+	if info != nil && mustWriteInPlace(err) {
+		fmt.Fprintf(os.Stderr, "warning: %v; writing %s in place instead\n", err, path)
+
+		return writeInPlace(path, write)
+	}
+
+	return err
+}
+
+// rename is os.Rename, replaced during tests.
+var rename = os.Rename
+
+// renameOver writes the output of write to a temporary file and renames it over the file at path,
+// which is described by info if it exists.
+func renameOver(path string, info os.FileInfo, write func(w io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".freeblock-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o644)
+	if info != nil {
+		mode = info.Mode().Perm()
+		if err = chown(tmp, info); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// mustWriteInPlace returns whether err from renameOver means that the file can't be replaced by
+// renaming, but might still be written to directly.
+func mustWriteInPlace(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EXDEV)
+}
+
+// writeInPlace overwrites the file at path with the output of write, keeping its mode and owner.
+// The output is collected first, so that the file isn't truncated if write fails.
+func writeInPlace(path string, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// sidecarPath returns the path of a file that freeblock keeps next to the hosts file, such as the
+// lock file. Symlinks are resolved first, so that every path to the hosts file shares the same
+// sidecar files.
+func sidecarPath(hostsFile, suffix string) (string, error) {
+	target, err := filepath.EvalSymlinks(hostsFile)
+	if err != nil {
+		return "", err
+	}
+
+	return target + suffix, nil
+}
@@ -0,0 +1,145 @@
+package cmds_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestBlock_atomicWrite(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks and Unix permissions aren't available on Windows")
+	}
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real_hosts")
+	link := filepath.Join(dir, "hosts")
+
+	err := os.WriteFile(target, []byte("127.0.0.1 localhost\n"), 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile is subject to the umask, so set the mode explicitly.
+	if err = os.Chmod(target, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Symlink("real_hosts", link); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cmds.Block([]string{"example.com"}, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link should still point to the same file.
+	dest, err := os.Readlink(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != "real_hosts" {
+		t.Errorf("symlink changed to point to %q", dest)
+	}
+
+	// The file should have the new content and the old mode.
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode changed from 0640 to %#o", info.Mode().Perm())
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff("127.0.0.1 localhost\n0.0.0.0 example.com\n:: example.com\n", string(got))
+	if diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+
+	// No temporary files should be left behind, only the lock file.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	diff = cmp.Diff([]string{"hosts", "real_hosts", "real_hosts.freeblock.lock"}, names)
+	if diff != "" {
+		t.Error("unexpected files in directory (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_busyRename(t *testing.T) {
+	// A bind-mounted file, like /etc/hosts in a container, can't be renamed over.
+	defer cmds.SetRename(func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EBUSY}
+	})()
+
+	dir := t.TempDir()
+	hostsFile := filepath.Join(dir, "hosts")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n")
+	before, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cmds.Block([]string{"example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	checkFile(t, hostsFile, "127.0.0.1 localhost\n0.0.0.0 example.com\n:: example.com\n")
+
+	// The file should have been written in place, without leaving a temporary file behind.
+	after, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("the hosts file was replaced instead of written in place")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "hosts" && e.Name() != "hosts.freeblock.lock" {
+			t.Errorf("unexpected file %s", e.Name())
+		}
+	}
+}
+
+func TestBlock_unchanged(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n0.0.0.0 example.com\n:: example.com\n")
+	before, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cmds.Block([]string{"example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing changed, so the file shouldn't have been replaced.
+	after, err := os.Stat(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) || !after.ModTime().Equal(before.ModTime()) {
+		t.Error("the hosts file was written even though nothing changed")
+	}
+}
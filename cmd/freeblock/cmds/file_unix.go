@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package cmds
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives f the same owner and group as the file described by info, unless it already has
+// them. Only root can give a file away, so this keeps working for other users when nothing needs
+// to change.
+func chown(f *os.File, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	cur, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if curSt, ok := cur.Sys().(*syscall.Stat_t); ok && curSt.Uid == st.Uid && curSt.Gid == st.Gid {
+		return nil
+	}
+
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
+
+// syncDir flushes a directory to disk, so that a rename inside it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if err != nil {
+		d.Close()
+
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,13 @@
+package cmds
+
+import "os"
+
+// chown does nothing on Windows, which doesn't have Unix file owners.
+func chown(*os.File, os.FileInfo) error {
+	return nil
+}
+
+// syncDir does nothing on Windows, where directories can't be synced.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// session records the changes made by an 'open' command, so that they can be reverted by another
+// freeblock process if the 'open' process dies before it can revert them itself.
+type session struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	// Boot is when the system was booted before the session started, or the zero time if that
+	// isn't known. If the system has been rebooted since, the PID may belong to another process.
+	Boot time.Time `json:"boot"`
+	// Expires is when the domains should be blocked again, or the zero time if the session lasts
+	// until it's interrupted.
+	Expires time.Time `json:"expires"`
+	Changes []change  `json:"changes"`
+}
+
+// recoveryGrace is how long after its expiry a session is considered abandoned, even if its process
+// is still running. This gives 'open --for' time to revert the changes itself.
+const recoveryGrace = time.Minute
+
+// bootTolerance is how far apart two readings of the boot time can be before they're taken to be
+// from different boots. The boot time is worked out from the wall clock, so it moves a little when
+// the clock is adjusted.
+const bootTolerance = time.Minute
+
+// bootTime returns when the system was booted, and whether that is known.
+var bootTime = systemBootTime
+
+// rebooted returns whether the system has been rebooted since the session started.
+func (s *session) rebooted() bool {
+	boot, ok := bootTime()
+	if s.Boot.IsZero() || !ok {
+		return false
+	}
+	d := boot.Sub(s.Boot)
+
+	return d > bootTolerance || d < -bootTolerance
+}
+
+// abandoned returns whether the session's process is no longer going to revert its changes.
+func (s *session) abandoned(now time.Time) bool {
+	if s.PID == os.Getpid() {
+		return false
+	}
+	if s.rebooted() || !processAlive(s.PID) {
+		return true
+	}
+
+	return !s.Expires.IsZero() && now.After(s.Expires.Add(recoveryGrace))
+}
+
+func (s *session) String() string {
+	domains := make([]string, len(s.Changes))
+	for i, c := range s.Changes {
+		domains[i] = c.Domain
+	}
+
+	return fmt.Sprintf(
+		"'open' session started by process %d at %s for %v",
+		s.PID, s.Started.Format(time.RFC3339), domains,
+	)
+}
+
+// journal is the list of 'open' sessions whose changes haven't been reverted yet. It is kept in a
+// sidecar file next to the hosts file, and must only be read or written while holding the lock on
+// the hosts file.
+type journal struct {
+	Sessions []*session `json:"sessions"`
+
+	path      string
+	recovered []*session // sessions reverted by recover
+	added     bool       // whether a session has been added since the journal was read
+	dirty     bool       // whether the journal has changed since it was read
+}
+
+func readJournal(hostsFile string) (*journal, error) {
+	path, err := sidecarPath(hostsFile, ".freeblock.journal")
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	j := &journal{path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	err = json.Unmarshal(b, j)
+	if err != nil {
+		return nil, &ErrParse{File: path, Err: err}
+	}
+
+	return j, nil
+}
+
+// write replaces the journal file with the sessions. If there are none, the file is removed.
+func (j *journal) write(sessions []*session) error {
+	if len(sessions) == 0 {
+		err := os.Remove(j.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("write journal: %w", err)
+		}
+
+		return nil
+	}
+
+	err := replaceFile(j.path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+
+		return enc.Encode(journal{Sessions: sessions})
+	})
+	if err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	return nil
+}
+
+// add records a new session.
+func (j *journal) add(s *session) {
+	j.Sessions = append(j.Sessions, s)
+	j.added = true
+	j.dirty = true
+}
+
+// remove forgets a session once its changes have been reverted.
+func (j *journal) remove(s *session) {
+	for i, other := range j.Sessions {
+		if other.PID == s.PID && other.Started.Equal(s.Started) {
+			j.Sessions = append(j.Sessions[:i], j.Sessions[i+1:]...)
+			j.dirty = true
+
+			return
+		}
+	}
+}
+
+// recover reverts the changes of abandoned sessions in f and removes them from the journal.
+func (j *journal) recover(f *hosts.File, now time.Time) {
+	var kept []*session
+
+	for _, s := range j.Sessions {
+		if !s.abandoned(now) {
+			kept = append(kept, s)
+
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Blocking domains left open by abandoned %v\n", s)
+		revertChanges(f, s.Changes)
+		j.recovered = append(j.recovered, s)
+		j.dirty = true
+	}
+
+	j.Sessions = kept
+}
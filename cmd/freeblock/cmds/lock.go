@@ -0,0 +1,102 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockTimeout is how long to wait for another freeblock process to release the hosts file.
+var lockTimeout = 10 * time.Second
+
+const lockPollInterval = 50 * time.Millisecond
+
+// errWouldBlock is returned by tryLock when another process holds the lock.
+var errWouldBlock = errors.New("lock is held by another process")
+
+// lockFile takes an advisory lock on the hosts file, so that concurrent freeblock processes don't
+// overwrite each other's changes. The lock is taken on a sidecar file next to the hosts file, which
+// also records the PID of the process holding the lock. If the lock can't be taken within timeout,
+// an *ErrLocked is returned. The returned function releases the lock.
+func lockFile(hostsFile string, timeout time.Duration) (unlock func() error, err error) {
+	path, err := sidecarPath(hostsFile, ".freeblock.lock")
+	if err != nil {
+		return nil, fmt.Errorf("lock hosts file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var f *os.File
+		f, err = tryLock(path)
+		if err == nil {
+			// Record our PID so that other processes can say who holds the lock.
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			if err != nil {
+				unlockFile(f)
+
+				return nil, fmt.Errorf("lock hosts file: %w", err)
+			}
+
+			return func() error { return unlockFile(f) }, nil
+		}
+		if !errors.Is(err, errWouldBlock) {
+			return nil, fmt.Errorf("lock hosts file: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, &ErrLocked{hostsFile, readLockPID(path), timeout}
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// withLock runs f while holding the lock on the hosts file.
+func withLock(hostsFile string, f func() error) error {
+	unlock, err := lockFile(hostsFile, lockTimeout)
+	if err != nil {
+		return err
+	}
+
+	err = f()
+	if e := unlock(); e != nil && err == nil {
+		err = fmt.Errorf("unlock hosts file: %w", e)
+	}
+
+	return err
+}
+
+// readLockPID returns the PID recorded in the lock file, or 0 if it can't be read.
+func readLockPID(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
+
+// ErrLocked is returned when another process held the lock on the hosts file for too long.
+type ErrLocked struct {
+	HostsFile string
+	// PID is the process holding the lock, or 0 if it's not known.
+	PID     int
+	Timeout time.Duration
+}
+
+func (e *ErrLocked) Error() string {
+	holder := "another process"
+	if e.PID != 0 {
+		holder = fmt.Sprintf("process %d", e.PID)
+	}
+
+	return fmt.Sprintf(
+		"%s is locked by %s (gave up after %v)", e.HostsFile, holder, e.Timeout,
+	)
+}
@@ -0,0 +1,59 @@
+package cmds_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_locked(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+
+	const content = "127.0.0.1 localhost\n"
+	err := os.WriteFile(hostsFile, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cmds.SetLockTimeout(100 * time.Millisecond)()
+
+	unlock, err := cmds.LockFile(hostsFile, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block should give up while we hold the lock, and name us as the holder.
+	_, err = cmds.Block([]string{"example.com"}, hostsFile)
+	var as *cmds.ErrLocked
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrLocked, got %v", err)
+	}
+	want := fmt.Sprintf("%s is locked by process %d (gave up after 100ms)", hostsFile, os.Getpid())
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Error("unexpected error (-want +got):\n" + diff)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(content, string(got)); diff != "" {
+		t.Error("hosts file changed while locked (-want +got):\n" + diff)
+	}
+
+	// Once the lock is released, Block should go through.
+	if err = unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cmds.Block([]string{"example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+// +build !windows
+
+package cmds
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock opens the lock file and takes an exclusive flock on it without waiting. If another
+// process holds the lock, errWouldBlock is returned.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errWouldBlock
+		}
+
+		return nil, err
+	}
+
+	// Clear the PID left by the previous holder.
+	if err = f.Truncate(0); err != nil {
+		unlockFile(f)
+
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// unlockFile releases a lock taken by tryLock. The lock file is left in place, because removing it
+// would let another process lock a file that is about to be unlinked.
+func unlockFile(f *os.File) error {
+	// Clear our PID first, since we won't be holding the lock anymore.
+	_ = f.Truncate(0)
+
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
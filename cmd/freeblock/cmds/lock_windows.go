@@ -0,0 +1,75 @@
+package cmds
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errLockViolation syscall.Errno = 33 // ERROR_LOCK_VIOLATION
+)
+
+// lockRange returns the byte range of the lock file that is locked. Windows locks are mandatory,
+// so the range starts at 4GiB to leave the PID readable by other processes.
+func lockRange() *syscall.Overlapped {
+	return &syscall.Overlapped{OffsetHigh: 1}
+}
+
+// tryLock opens the lock file and takes an exclusive LockFileEx lock on it without waiting. If
+// another process holds the lock, errWouldBlock is returned. Windows releases the lock if the
+// process dies, so a crash doesn't leave the hosts file locked.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r, _, err := procLockFileEx.Call(
+		f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0,
+		uintptr(unsafe.Pointer(lockRange())),
+	)
+	if r == 0 {
+		f.Close()
+		if errors.Is(err, errLockViolation) {
+			return nil, errWouldBlock
+		}
+
+		return nil, err
+	}
+
+	// Clear the PID left by the previous holder.
+	if err = f.Truncate(0); err != nil {
+		unlockFile(f)
+
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// unlockFile releases a lock taken by tryLock. The lock file is left in place, because removing it
+// would let another process lock a file that is about to be deleted.
+func unlockFile(f *os.File) error {
+	// Clear our PID first, since we won't be holding the lock anymore.
+	_ = f.Truncate(0)
+
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(lockRange())))
+	if r == 0 {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
@@ -6,71 +6,227 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
 )
 
 // OpenCmd is a command that temporarily unblocks domains in a hosts file.
 var OpenCmd = &cobra.Command{
-	Use:   "open DOMAIN [DOMAIN...]",
+	Use:   "open [DOMAIN...]",
 	Short: "open domains while the command is running",
 	Long: `Temporarily unblock domains using the 'unblock' command, and then block them
-again before exiting when a SIGINT is received.
+again before exiting when a SIGINT is received. With --for, the domains are
+blocked again automatically once the duration has passed.
+
+If a time range refuses some of the domains, nothing is opened unless --partial
+is given, in which case only the allowed domains are opened. With
+--with-subdomains, common subdomains like www.DOMAIN are opened too, and with
+--service, all of the domains of a service like reddit are opened. Domains may
+also be glob patterns like '*.reddit.com', or regular expressions with --regex.
+
+Only the lines changed by 'open' are reverted, so other changes made to the hosts
+file in the meantime are kept.
 `,
-	Args: cobra.MinimumNArgs(1),
+	Args: domainArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		domains, err := withServices(args)
+		if err != nil {
+			exitWithReport(nil, err)
+
+			return
+		}
+
 		osSignals := make(chan os.Signal, 1)
 		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
 
-		if err := Open(args, hostsFile, osSignals); err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
-		}
+		exitWithReport(Open(domains, hostsFile, osSignals, openFor, DefaultClock{}))
 	},
 }
 
+var openFor time.Duration
+
 func init() {
-	OpenCmd.Flags().StringVar(
-		&hostsFile, "hosts-file", defaultHostsFile, "Change the default hosts file.")
+	addFileFlags(OpenCmd)
+	addSinkFlag(OpenCmd)
+	addOutputFlag(OpenCmd)
+	addScheduleFlags(OpenCmd)
+	addPartialFlag(OpenCmd)
+	addSubdomainFlags(OpenCmd)
+	addServiceFlags(OpenCmd)
+	addPatternFlag(OpenCmd)
+	OpenCmd.Flags().DurationVar(
+		&openFor, "for", 0, "Block the domains again after this long (e.g. 20m).")
 }
 
 // Open temporarily unblocks the domains in the hostsFile, and then closes them when it receives a
-// signal on osSignals.
-func Open(domains []string, hostsFile string, osSignals <-chan os.Signal) (err error) {
-	// Back up the original lines in the hosts file, so that we can revert at the end.
-	backupLines, err := readLines(hostsFile)
-	if err != nil {
-		return fmt.Errorf("backup hosts file: %w", err)
+// signal on osSignals or, if d is not zero, once d has passed on the clock. Only the lines changed
+// by Open are reverted, so other changes made to the hosts file in the meantime are kept.
+func Open(
+	domains []string, hostsFile string, osSignals <-chan os.Signal, d time.Duration, clock Clock,
+) (r *Report, err error) {
+	// Record the changes in the journal before making them, so that another freeblock process can
+	// revert them if this one dies.
+	var (
+		s         *session
+		changes   []change
+		refused   error
+		families  [][]string
+		unmatched []string
+	)
+
+	err = editFile(hostsFile, clock, func(f *hosts.File, j *journal) error {
+		var e error
+		families, unmatched, e = resolveFamilies(f, domains)
+		if e != nil {
+			return e
+		}
+		changes, e = unblockFile(f, families, clock)
+		refused, e = splitRefused(e)
+		if e != nil {
+			return fmt.Errorf("unblock domains: %w", e)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		s = &session{PID: os.Getpid(), Started: clock.Now(), Changes: changes}
+		if boot, ok := bootTime(); ok {
+			s.Boot = boot
+		}
+		if d != 0 {
+			s.Expires = s.Started.Add(d)
+		}
+		j.add(s)
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrChangesPending) {
+		if refusals(err) == nil {
+			return newReport(nil, nil), err
+		}
+
+		// Nothing was changed, so the domains that weren't refused were skipped.
+		return newReport(append(flatten(families), unmatched...), nil), err
+	}
+	domains = flatten(families)
+	r = newReport(append(flatten(families), unmatched...), changes)
+	if err != nil || dryRun || s == nil && refused != nil {
+		if err == nil {
+			err = refused
+		}
+
+		return r, err
 	}
+
 	defer func() {
-		var as *ErrBlockTiming
-		if errors.As(err, &as) {
-			// No need to restore because we were kept from unblocking.
+		if s == nil {
 			return
 		}
 
-		fmt.Fprintln(os.Stderr, "\nRestoring old hosts file...")
-		e := writeLines(backupLines, hostsFile)
-		if e != nil && err == nil {
-			err = fmt.Errorf("restore backed up hosts file: %w", err)
+		fmt.Fprintln(os.Stderr, "\nBlocking domains again...")
+		e := editFile(hostsFile, clock, func(f *hosts.File, j *journal) error {
+			revertChanges(f, s.Changes)
+			j.remove(s)
+
+			return nil
+		})
+		if e != nil {
+			err = fmt.Errorf("block domains again: %w", e)
 
 			return
 		}
 		fmt.Fprintln(os.Stderr, "\tdone.")
 	}()
 
-	err = Unblock(domains, hostsFile, DefaultNower{})
-	if err != nil {
-		return fmt.Errorf("unblock domains: %w", err)
+	if refused != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", refused)
+	}
+	isRefused := make(map[string]bool)
+	for _, t := range refusals(refused) {
+		isRefused[t.Domain] = true
 	}
-
 	fmt.Fprintln(os.Stderr, "Domains temporarily unblocked:")
 	for _, domain := range domains {
-		fmt.Fprintf(os.Stderr, "- %s\n", domain)
+		if !isRefused[domain] {
+			fmt.Fprintf(os.Stderr, "- %s\n", domain)
+		}
+	}
+
+	if d == 0 {
+		// Wait for a SIGINT or SIGTERM before blocking again and exiting.
+		<-osSignals
+
+		return r, refused
+	}
+
+	countdown(d, osSignals, clock)
+
+	return r, refused
+}
+
+const countdownInterval = time.Second
+
+// countdown waits until d has passed or a signal is received, showing the time left on stderr.
+func countdown(d time.Duration, osSignals <-chan os.Signal, clock Clock) {
+	deadline := clock.Now().Add(d)
+
+	for {
+		left := deadline.Sub(clock.Now())
+		if left <= 0 {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "\rBlocking again in %v ", left.Round(time.Second))
+
+		wait := countdownInterval
+		if left < wait {
+			wait = left
+		}
+
+		select {
+		case <-osSignals:
+			return
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// revertChanges undoes changes made by unblockFile. A changed line that is still the way
+// unblockFile left it is put back the way it was. If the line has been edited since, its domain is
+// blocked again with blockFile instead. All other lines are left alone.
+func revertChanges(f *hosts.File, changes []change) {
+	var reblock []string
+	reverted := make(map[*hosts.Entry]bool, len(changes))
+
+	for _, c := range changes {
+		i := findLine(f, c.After, reverted)
+		if i == -1 {
+			reblock = append(reblock, c.Domain)
+
+			continue
+		}
+
+		e := hosts.ParseLine(c.Before)
+		e.Num = f.Entries[i].Num
+		f.Entries[i] = e
+		reverted[e] = true
 	}
 
-	// Wait for a SIGINT or SIGTERM before restoring the old file and exiting.
-	<-osSignals
+	if len(reblock) != 0 {
+		// A broken sink line shouldn't stop the domains from being blocked again.
+		blockFile(f, fileSinksOrDefault(f), reblock)
+	}
+}
+
+// findLine returns the index of the first entry in f that renders as l and isn't in skip, or -1.
+func findLine(f *hosts.File, l hosts.Line, skip map[*hosts.Entry]bool) int {
+	for i, e := range f.Entries {
+		if !skip[e] && e.Line() == l {
+			return i
+		}
+	}
 
-	return nil
+	return -1
 }
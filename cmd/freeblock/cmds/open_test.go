@@ -1,12 +1,14 @@
 package cmds_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
@@ -21,13 +23,20 @@ func TestOpenCmd(t *testing.T) {
 
 	// Run OpenCmd in a separate routine.
 	osSignals := make(chan os.Signal, 1)
-	var g errgroup.Group
+	var (
+		g      errgroup.Group
+		report *cmds.Report
+	)
 	g.Go(func() error {
-		return cmds.Open(
+		var err error
+		report, err = cmds.Open(
 			[]string{"google.com", "example.com", "internal.example.com", "github.com"},
 			hostsFile,
 			osSignals,
+			0, cmds.DefaultClock{},
 		)
+
+		return err
 	})
 
 	// Wait until the file has been changed, and then check the file.
@@ -39,6 +48,243 @@ func TestOpenCmd(t *testing.T) {
 	if err := g.Wait(); err != nil {
 		t.Fatal(err)
 	}
+
+	// Check the report.
+	want := &cmds.Report{
+		Changed: []string{"google.com", "internal.example.com"},
+		Skipped: []string{"example.com", "github.com"},
+		Refused: []cmds.Refusal{},
+	}
+	if diff := cmp.Diff(want, report, cmpopts.IgnoreUnexported(cmds.Report{})); diff != "" {
+		t.Error("unexpected report (-want +got):\n" + diff)
+	}
+}
+
+func TestOpen_keepsOtherChanges(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `0.0.0.0   google.com
+0.0.0.0   internal.example.com # 1.2.3.4
+127.0.0.1 devicename
+`)
+
+	// Run Open in a separate routine.
+	osSignals := make(chan os.Signal, 1)
+	var g errgroup.Group
+	g.Go(func() error {
+		_, err := cmds.Open(
+			[]string{"google.com", "internal.example.com"}, hostsFile, osSignals,
+			0, cmds.DefaultClock{},
+		)
+
+		return err
+	})
+
+	// Wait until the file has been changed, and then change it some more.
+	time.Sleep(10 * time.Millisecond)
+	writeString(t, hostsFile, `#0.0.0.0   google.com
+1.2.3.4   internal.example.com   # edited by hand
+127.0.0.1 devicename
+1.1.1.1   new.example.com
+`)
+
+	// Kill the routine and check the error.
+	osSignals <- os.Interrupt
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The edits should survive, and the domains should be blocked again.
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `0.0.0.0   google.com
+0.0.0.0   internal.example.com   # edited by hand # 1.2.3.4
+127.0.0.1 devicename
+1.1.1.1   new.example.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+
+	// The saved address comes after the comment that was added by hand, so it can be restored.
+	_, err = cmds.Unblock([]string{"internal.example.com"}, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, hostsFile, `0.0.0.0   google.com
+1.2.3.4   internal.example.com   # edited by hand
+127.0.0.1 devicename
+1.1.1.1   new.example.com
+`)
+}
+
+func TestOpen_for(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = "0.0.0.0 google.com\n"
+	writeString(t, hostsFile, content)
+
+	start := time.Date(2021, 10, 4, 12, 0, 0, 0, time.Local)
+	clock := &MockClock{T: start}
+
+	// The domain should be open while we wait.
+	var waited time.Duration
+	clock.OnAfter = func(d time.Duration) {
+		waited += d
+
+		got, err := os.ReadFile(hostsFile)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff("#0.0.0.0 google.com\n", string(got)); diff != "" {
+			t.Error("domain not open while waiting (-want +got):\n" + diff)
+		}
+	}
+
+	const d = 90*time.Second + 500*time.Millisecond
+	_, err := cmds.Open([]string{"google.com"}, hostsFile, nil, d, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if waited != d {
+		t.Errorf("waited %v instead of 1m30.5s", waited)
+	}
+
+	// The domain should be blocked again.
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(content, string(got)); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+}
+
+func TestOpen_weekdays(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "0.0.0.0 reddit.com #freeblock:sat+sun@00-24\n")
+	fileShouldNotChange(t, hostsFile)
+
+	// October 9, 2021 was a Saturday.
+	clock := &MockClock{T: time.Date(2021, 10, 9, 12, 0, 0, 0, time.Local)}
+
+	_, err := cmds.Open([]string{"reddit.com"}, hostsFile, nil, time.Minute, clock)
+	var as *cmds.ErrBlockTiming
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrBlockTiming, got %v", err)
+	}
+}
+
+func TestOpen_refused(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0 example.com # 5.6.7.8
+`)
+	fileShouldNotChange(t, hostsFile)
+
+	clock := &MockClock{T: time.Date(2021, 10, 4, 10, 30, 0, 0, time.Local)}
+
+	r, err := cmds.Open([]string{"reddit.com", "example.com"}, hostsFile, nil, time.Second, clock)
+	var as *cmds.ErrBlockTiming
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrBlockTiming, got %v", err)
+	}
+	want := &cmds.Report{
+		Changed: []string{},
+		Skipped: []string{"reddit.com", "example.com"},
+		Refused: []cmds.Refusal{},
+	}
+	if diff := cmp.Diff(want, r, cmpopts.IgnoreUnexported(cmds.Report{})); diff != "" {
+		t.Error("unexpected report (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestOpen_partial(t *testing.T) {
+	defer cmds.SetPartial(true)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0 example.com # 5.6.7.8
+`
+	writeString(t, hostsFile, content)
+
+	clock := &MockClock{T: time.Date(2021, 10, 4, 10, 30, 0, 0, time.Local)}
+
+	// Only the allowed domain should be open while we wait.
+	clock.OnAfter = func(time.Duration) {
+		got, err := os.ReadFile(hostsFile)
+		if err != nil {
+			t.Error(err)
+		}
+		want := `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+5.6.7.8 example.com
+`
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Error("unexpected hosts file while open (-want +got):\n" + diff)
+		}
+	}
+
+	r, err := cmds.Open([]string{"reddit.com", "example.com"}, hostsFile, nil, time.Second, clock)
+	var as *cmds.ErrRefused
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrRefused, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"example.com"}, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+
+	// Everything should be blocked again.
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(content, string(got)); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+}
+
+// MockClock is a Clock that only moves forward when After is called. After returns immediately,
+// as if the time had passed.
+type MockClock struct {
+	T time.Time
+
+	// OnAfter, if set, is called each time After is called.
+	OnAfter func(d time.Duration)
+}
+
+func (m *MockClock) Now() time.Time {
+	return m.T
+}
+
+func (m *MockClock) After(d time.Duration) <-chan time.Time {
+	if m.OnAfter != nil {
+		m.OnAfter(d)
+	}
+	m.T = m.T.Add(d)
+
+	c := make(chan time.Time, 1)
+	c <- m.T
+
+	return c
+}
+
+func writeString(t *testing.T, file, s string) {
+	t.Helper()
+
+	err := os.WriteFile(file, []byte(s), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 func fileShouldNotChange(t *testing.T, file string) {
@@ -0,0 +1,283 @@
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// Exit codes returned by the commands.
+const (
+	ExitOK = 0
+	// ExitError is returned for errors that don't have a more specific code.
+	ExitError = 1
+	// ExitChangesPending is returned in dry-run mode if the command would change the hosts file.
+	ExitChangesPending = 2
+	// ExitRefused is returned if a time range didn't allow a domain to be unblocked.
+	ExitRefused = 3
+	// ExitPermission is returned if the hosts file or its sidecar files can't be accessed.
+	ExitPermission = 4
+	// ExitParse is returned if a file used by freeblock or a #freeblock: directive can't be parsed.
+	ExitParse = 5
+	// ExitLocked is returned if another freeblock process held the lock on the hosts file for too
+	// long.
+	ExitLocked = 6
+)
+
+// exitCode returns the exit code for the error.
+func exitCode(err error) int {
+	var (
+		timing    *ErrBlockTiming
+		parse     *ErrParse
+		directive *hosts.ParseError
+		locked    *ErrLocked
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrChangesPending):
+		return ExitChangesPending
+	case errors.As(err, &timing):
+		return ExitRefused
+	case errors.Is(err, os.ErrPermission):
+		return ExitPermission
+	case errors.As(err, &parse), errors.As(err, &directive):
+		return ExitParse
+	case errors.As(err, &locked):
+		return ExitLocked
+	default:
+		return ExitError
+	}
+}
+
+// exitOnError prints the error and exits with the matching exit code, unless err is nil.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+
+	if !errors.Is(err, ErrChangesPending) {
+		// The diff has already been printed, so there's no need to say anything else.
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// ErrParse is returned when a file used by freeblock can't be parsed.
+type ErrParse struct {
+	File string
+	// Line is the line number where the problem is, or 0 if it's not known.
+	Line int
+	Err  error
+}
+
+func (e *ErrParse) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("parse %s: %v", e.File, e.Err)
+	}
+
+	return fmt.Sprintf("parse %s: line %d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ErrParse) Unwrap() error {
+	return e.Err
+}
+
+// The output formats.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// output is the output format chosen with --output.
+var output = outputText
+
+// outputValue is a flag.Value that sets output, accepting only the known formats.
+type outputValue struct{}
+
+func (outputValue) String() string {
+	return output
+}
+
+func (outputValue) Set(s string) error {
+	if s != outputText && s != outputJSON {
+		return fmt.Errorf("unknown output format %q (must be %q or %q)", s, outputText, outputJSON)
+	}
+	output = s
+
+	return nil
+}
+
+func (outputValue) Type() string {
+	return "format"
+}
+
+// addOutputFlag adds the flag for choosing the output format to cmd.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().VarP(
+		outputValue{}, "output", "o",
+		`Output format, either "text" or "json". See 'freeblock help exit-codes'.`)
+}
+
+// Report is the result of a command that changes the hosts file, printed with --output json.
+type Report struct {
+	// Changed lists the requested domains whose host lines were changed.
+	Changed []string `json:"changed"`
+	// Skipped lists the requested domains that were already in the requested state.
+	Skipped []string `json:"skipped"`
+	// Refused lists the requested domains that a time range didn't allow to be unblocked.
+	Refused []Refusal `json:"refused"`
+
+	reportError
+}
+
+// Refusal describes a domain that a time range didn't allow to be unblocked.
+type Refusal struct {
+	Domain string `json:"domain"`
+	// Line is the line number of the host line with the time range.
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// reportError holds the outcome of a command in a JSON report.
+type reportError struct {
+	// Error is the error message, if the command failed.
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func (r *reportError) fail(err error) {
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.ExitCode = exitCode(err)
+}
+
+// newReport returns a Report saying which of the requested domains were changed and which were
+// skipped.
+func newReport(domains []string, changes []change) *Report {
+	changed := make(map[string]bool)
+	for _, c := range changes {
+		for _, h := range hosts.ParseLine(c.After).Hostnames {
+			changed[h] = true
+		}
+	}
+
+	r := &Report{Changed: []string{}, Skipped: []string{}, Refused: []Refusal{}}
+	for _, domain := range domains {
+		if changed[domain] {
+			r.Changed = append(r.Changed, domain)
+		} else {
+			r.Skipped = append(r.Skipped, domain)
+		}
+	}
+
+	return r
+}
+
+// exitWithReport finishes a command that changes the hosts file. With --output json the report is
+// printed to stdout, including the error if there is one. Otherwise the error is printed to
+// stderr. Either way, the process exits with the matching exit code if there was an error.
+func exitWithReport(r *Report, err error) {
+	if output != outputJSON {
+		exitOnError(err)
+
+		return
+	}
+
+	if r == nil {
+		r = newReport(nil, nil)
+	}
+	for _, t := range refusals(err) {
+		r.Refused = append(r.Refused, Refusal{t.Domain, t.LineNum, t.Error()})
+		r.Skipped = remove(r.Skipped, t.Domain)
+	}
+	r.fail(err)
+
+	exitWithJSON(r, r.ExitCode)
+}
+
+// refusals returns the refused domains in err, if it is or wraps an *ErrRefused or *ErrBlockTiming.
+func refusals(err error) []*ErrBlockTiming {
+	var refused *ErrRefused
+	if errors.As(err, &refused) {
+		return refused.Refusals
+	}
+	var timing *ErrBlockTiming
+	if errors.As(err, &timing) {
+		return []*ErrBlockTiming{timing}
+	}
+
+	return nil
+}
+
+// remove returns arr without any elements equal to v.
+func remove(arr []string, v string) []string {
+	out := arr[:0]
+	for _, a := range arr {
+		if a != v {
+			out = append(out, a)
+		}
+	}
+
+	return out
+}
+
+// exitWithJSON prints v as JSON to stdout and exits with code, unless it's zero.
+func exitWithJSON(v interface{}, code int) {
+	if err := writeJSON(os.Stdout, v); err != nil {
+		exitOnError(err)
+	}
+	if code != ExitOK {
+		os.Exit(code)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// ExitCodesHelp is a help topic that documents the exit codes.
+var ExitCodesHelp = &cobra.Command{
+	Use:   "exit-codes",
+	Short: "the exit codes used by freeblock",
+	Long: `The freeblock commands exit with the following codes:
+
+  0  success
+  1  an error without a more specific code, including invalid arguments
+  2  with --dry-run or 'diff', there are changes pending
+  3  a #freeblock: time range didn't allow a domain to be unblocked
+  4  permission denied on the hosts file or one of its sidecar files
+  5  a file used by freeblock or a #freeblock: directive couldn't be parsed
+  6  another freeblock process held the lock on the hosts file for too long
+
+With --output json, block, unblock, open and enforce print an object like this
+to stdout, even if the command fails:
+
+  {
+    "changed": ["example.com"],
+    "skipped": ["google.com"],
+    "refused": [{"domain": "reddit.com", "line": 3, "reason": "..."}],
+    "error": "...",
+    "exit_code": 3
+  }
+
+"changed" lists the requested domains whose lines were changed, "skipped" the
+ones that were already in the requested state, and "refused" the ones a time
+range didn't allow to be unblocked. For enforce, "changed" lists the domains on
+every line it changed. "error" is left out on success.
+
+status prints {"domains": [...]} with an object for each domain, along with
+"error" and "exit_code" like above.
+`,
+}
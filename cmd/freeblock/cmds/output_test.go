@@ -0,0 +1,66 @@
+package cmds_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   error
+		want int
+	}{
+		"nil":     {nil, cmds.ExitOK},
+		"other":   {errors.New("oops"), cmds.ExitError},
+		"pending": {cmds.ErrChangesPending, cmds.ExitChangesPending},
+		"refused": {fmt.Errorf("unblock: %w", &cmds.ErrBlockTiming{}), cmds.ExitRefused},
+		"permission": {
+			&os.PathError{Op: "open", Path: "hosts", Err: os.ErrPermission}, cmds.ExitPermission,
+		},
+		"parse":  {&cmds.ErrParse{File: "journal", Err: errors.New("bad")}, cmds.ExitParse},
+		"locked": {&cmds.ErrLocked{HostsFile: "hosts"}, cmds.ExitLocked},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := cmds.ExitCode(tc.in)
+
+			diff := cmp.Diff(tc.want, got)
+			if diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestOutputFlag(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		restore, err := cmds.SetOutput(format)
+		restore()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", format, err)
+		}
+	}
+
+	for _, format := range []string{"yaml", "jsno", ""} {
+		err := cmds.BlockCmd.Flags().Set("output", format)
+		if err == nil {
+			t.Errorf("expected an error for %q", format)
+		}
+	}
+	if got := cmds.BlockCmd.Flags().Lookup("output").Value.String(); got != "text" {
+		t.Errorf("expected the output format to stay text, got %q", got)
+	}
+}
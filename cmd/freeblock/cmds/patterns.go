@@ -0,0 +1,148 @@
+package cmds
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// useRegex is set with --regex.
+var useRegex bool
+
+// addPatternFlag adds the flag for treating domains as regular expressions to cmd.
+func addPatternFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&useRegex, "regex", false,
+		"Treat each domain as a regular expression matched against the hostnames already in the\n"+
+			"hosts file.")
+}
+
+// isPattern returns whether a domain argument is a pattern matched against the hostnames already
+// in the hosts file. Arguments containing '*', '?' or '[' are glob patterns, and with --regex every
+// argument is a regular expression.
+func isPattern(arg string) bool {
+	return useRegex || strings.ContainsAny(arg, "*?[")
+}
+
+// compilePattern returns a function that reports whether a whole hostname matches the pattern.
+func compilePattern(pattern string) (func(hostname string) bool, error) {
+	if useRegex {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	return func(hostname string) bool {
+		ok, _ := path.Match(pattern, hostname)
+
+		return ok
+	}, nil
+}
+
+// matchHostnames returns the hostnames on the host lines of f that match the pattern, in order and
+// without duplicates.
+func matchHostnames(f *hosts.File, pattern string) ([]string, error) {
+	match, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, e := range f.Hosts() {
+		for _, h := range e.Hostnames {
+			if match(h) && !contains(out, h) {
+				out = append(out, h)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// resolveFamilies returns the families of hostnames for the domain arguments. Each pattern is
+// replaced with a family for each hostname in f that it matches, and the other arguments are
+// expanded with domainFamilies. Patterns that don't match anything are returned in unmatched.
+func resolveFamilies(
+	f *hosts.File, args []string,
+) (families [][]string, unmatched []string, err error) {
+	for _, arg := range args {
+		if !isPattern(arg) {
+			families = append(families, domainFamilies([]string{arg})...)
+
+			continue
+		}
+
+		hostnames, matchErr := matchHostnames(f, arg)
+		if matchErr != nil {
+			return nil, nil, matchErr
+		}
+		if len(hostnames) == 0 {
+			unmatched = append(unmatched, arg)
+		}
+		for _, h := range hostnames {
+			families = append(families, []string{h})
+		}
+	}
+
+	return families, unmatched, nil
+}
+
+// selector reports whether a hostname was selected by the domain arguments of a command, either by
+// name or with a pattern.
+type selector struct {
+	domains  []string
+	patterns []func(hostname string) bool
+}
+
+func newSelector(args []string) (*selector, error) {
+	s := &selector{}
+
+	for _, arg := range args {
+		if !isPattern(arg) {
+			s.domains = append(s.domains, arg)
+
+			continue
+		}
+
+		match, err := compilePattern(arg)
+		if err != nil {
+			return nil, err
+		}
+		s.patterns = append(s.patterns, match)
+	}
+
+	return s, nil
+}
+
+// all returns whether every hostname is selected because no arguments were given.
+func (s *selector) all() bool {
+	return len(s.domains) == 0 && len(s.patterns) == 0
+}
+
+// byName returns whether the hostname was given as a domain argument.
+func (s *selector) byName(hostname string) bool {
+	return contains(s.domains, hostname)
+}
+
+// byPattern returns whether the hostname matches one of the pattern arguments.
+func (s *selector) byPattern(hostname string) bool {
+	for _, match := range s.patterns {
+		if match(hostname) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package cmds_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+const patternHosts = `127.0.0.1 localhost
+1.2.3.4 www.reddit.com
+#0.0.0.0 old.reddit.com reddit.com
+0.0.0.0 www.tiktok.com
+0.0.0.0 tiktokcdn.com #freeblock:09-17
+`
+
+func TestBlock_glob(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, patternHosts)
+
+	r, err := cmds.Block([]string{"*.reddit.com", "*.youtube.com"}, hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"www.reddit.com", "old.reddit.com"}, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+	if diff := cmp.Diff([]string{"*.youtube.com"}, r.Skipped); diff != "" {
+		t.Error("unexpected skipped domains (-want +got):\n" + diff)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `127.0.0.1 localhost
+0.0.0.0 www.reddit.com # 1.2.3.4
+0.0.0.0 old.reddit.com reddit.com
+0.0.0.0 www.tiktok.com
+0.0.0.0 tiktokcdn.com #freeblock:09-17
+:: www.reddit.com
+:: old.reddit.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+func TestUnblock_glob(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, patternHosts)
+
+	now := time.Date(2021, 10, 4, 12, 0, 0, 0, time.Local)
+	_, err := cmds.Unblock([]string{"*tiktok*"}, hostsFile, MockNower{now})
+	if got := cmds.ExitCode(err); got != cmds.ExitRefused {
+		t.Fatalf("expected exit code %d, got %d (%v)", cmds.ExitRefused, got, err)
+	}
+
+	now = now.Add(6 * time.Hour)
+	r, err := cmds.Unblock([]string{"*tiktok*"}, hostsFile, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"www.tiktok.com", "tiktokcdn.com"}, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `127.0.0.1 localhost
+1.2.3.4 www.reddit.com
+#0.0.0.0 old.reddit.com reddit.com
+#0.0.0.0 www.tiktok.com
+#0.0.0.0 tiktokcdn.com #freeblock:09-17
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestUnblock_regex(t *testing.T) {
+	defer cmds.SetRegex(true)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, patternHosts)
+
+	// The whole hostname has to match, so this doesn't match tiktokcdn.com.
+	r, err := cmds.Unblock([]string{`(www\.)?tiktok\.com`}, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"www.tiktok.com"}, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+
+	_, err = cmds.Unblock([]string{"tiktok("}, hostsFile, MockNower{time.Now()})
+	if err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+
+	_, err = cmds.Block([]string{"[a-"}, hostsFile)
+	if err == nil {
+		t.Error("expected an error for an invalid glob pattern")
+	}
+}
+
+func TestStatus_pattern(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, patternHosts)
+
+	domains := []string{"*.reddit.com", "tiktokcdn.com"}
+	got, err := cmds.Status(domains, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// www.reddit.com isn't managed by freeblock yet, but the pattern would block it.
+	want := []cmds.DomainStatus{
+		{Domain: "www.reddit.com", Line: 2, State: cmds.StateUnblocked},
+		{Domain: "old.reddit.com", Line: 3, State: cmds.StateCommented},
+		{Domain: "tiktokcdn.com", Line: 5, State: cmds.StateBlocked, Schedule: []string{"09-17"}},
+	}
+	opts := []cmp.Option{cmpopts.EquateEmpty(), cmpopts.IgnoreFields(cmds.DomainStatus{}, "Forbidden")}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Error("unexpected statuses (-want +got):\n" + diff)
+	}
+}
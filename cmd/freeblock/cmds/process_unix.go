@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package cmds
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive returns whether a process with the PID is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	// EPERM means the process exists but belongs to someone else.
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
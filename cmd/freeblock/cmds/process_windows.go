@@ -0,0 +1,15 @@
+package cmds
+
+import "os"
+
+// processAlive returns whether a process with the PID is running.
+func processAlive(pid int) bool {
+	// On Windows, FindProcess fails if the process doesn't exist.
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+
+	return true
+}
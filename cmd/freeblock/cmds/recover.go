@@ -0,0 +1,55 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// RecoverCmd is a command that blocks domains left open by 'open' commands that didn't exit
+// cleanly.
+var RecoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "block domains left open by interrupted 'open' commands",
+	Long: `Block domains left open by 'open' commands that were killed, or that didn't
+get to block the domains again for some other reason (like a reboot).
+
+'open' keeps a journal of its changes next to the hosts file. A session in the
+journal is abandoned if its process is gone, or if it was started with --for
+and has run over by more than a minute.
+
+Every freeblock command recovers abandoned sessions before changing the hosts
+file, so this is only needed if nothing else is going to run. For example, run
+it when the machine boots.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := Recover(hostsFile, DefaultNower{})
+		exitOnError(err)
+		if n == 0 {
+			fmt.Fprintln(os.Stderr, "No abandoned sessions found.")
+		}
+	},
+}
+
+func init() {
+	addFileFlags(RecoverCmd)
+	addSinkFlag(RecoverCmd)
+}
+
+// Recover reverts the changes left behind by abandoned 'open' sessions on the hostsFile, returning
+// the number of sessions recovered.
+func Recover(hostsFile string, nower Nower) (int, error) {
+	var n int
+
+	err := editFile(hostsFile, nower, func(_ *hosts.File, j *journal) error {
+		n = len(j.recovered)
+
+		return nil
+	})
+
+	return n, err
+}
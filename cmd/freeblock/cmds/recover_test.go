@@ -0,0 +1,146 @@
+package cmds_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#0.0.0.0 google.com
+1.2.3.4 internal.example.com
+#0.0.0.0 example.com
+127.0.0.1 localhost
+`)
+
+	// The first session's process is gone, and the second has run over by more than the grace
+	// period. The third is still running (PID 1 always is) and hasn't expired yet.
+	journal := `{"sessions": [
+	{
+		"pid": 2147483647,
+		"started": "2021-10-04T11:00:00Z",
+		"expires": "0001-01-01T00:00:00Z",
+		"changes": [{
+			"domain": "google.com",
+			"before": "0.0.0.0 google.com",
+			"after": "#0.0.0.0 google.com"
+		}]
+	},
+	{
+		"pid": 1,
+		"started": "2021-10-04T11:00:00Z",
+		"expires": "2021-10-04T11:30:00Z",
+		"changes": [{
+			"domain": "internal.example.com",
+			"before": "0.0.0.0 internal.example.com # 1.2.3.4",
+			"after": "1.2.3.4 internal.example.com"
+		}]
+	},
+	{
+		"pid": 1,
+		"started": "2021-10-04T11:50:00Z",
+		"expires": "2021-10-04T12:50:00Z",
+		"changes": [{
+			"domain": "example.com",
+			"before": "0.0.0.0 example.com",
+			"after": "#0.0.0.0 example.com"
+		}]
+	}
+]}
+`
+	writeString(t, hostsFile+".freeblock.journal", journal)
+
+	now := time.Date(2021, 10, 4, 12, 0, 0, 0, time.UTC)
+	n, err := cmds.Recover(hostsFile, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("recovered %d sessions instead of 2", n)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `0.0.0.0 google.com
+0.0.0.0 internal.example.com # 1.2.3.4
+#0.0.0.0 example.com
+127.0.0.1 localhost
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+
+	// Once the last session is over, the journal should be removed.
+	n, err = cmds.Recover(hostsFile, MockNower{now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("recovered %d sessions instead of 1", n)
+	}
+	_, err = os.Stat(hostsFile + ".freeblock.journal")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected journal to be removed, got %v", err)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestRecover_rebooted(t *testing.T) {
+	defer cmds.SetBootTime(time.Date(2021, 10, 4, 11, 40, 0, 0, time.UTC))()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#0.0.0.0 google.com
+#0.0.0.0 example.com
+`)
+
+	// Both processes look alive, since PID 1 always is. But the first session started before the
+	// system was rebooted, so its PID now belongs to another process.
+	journal := `{"sessions": [
+	{
+		"pid": 1,
+		"started": "2021-10-04T11:00:00Z",
+		"boot": "2021-10-01T08:00:00Z",
+		"expires": "0001-01-01T00:00:00Z",
+		"changes": [{
+			"domain": "google.com",
+			"before": "0.0.0.0 google.com",
+			"after": "#0.0.0.0 google.com"
+		}]
+	},
+	{
+		"pid": 1,
+		"started": "2021-10-04T11:50:00Z",
+		"boot": "2021-10-04T11:40:00.5Z",
+		"expires": "0001-01-01T00:00:00Z",
+		"changes": [{
+			"domain": "example.com",
+			"before": "0.0.0.0 example.com",
+			"after": "#0.0.0.0 example.com"
+		}]
+	}
+]}
+`
+	writeString(t, hostsFile+".freeblock.journal", journal)
+
+	n, err := cmds.Recover(hostsFile, MockNower{time.Date(2021, 10, 4, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("recovered %d sessions instead of 1", n)
+	}
+	checkFile(t, hostsFile, `0.0.0.0 google.com
+#0.0.0.0 example.com
+`)
+}
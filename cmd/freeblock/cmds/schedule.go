@@ -0,0 +1,169 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// location is the time zone that schedules without their own zone are evaluated in. If it's nil,
+// the local time zone is used.
+var location *time.Location
+
+// locationValue is a flag.Value that sets location.
+type locationValue struct{}
+
+func (locationValue) String() string {
+	if location == nil {
+		return ""
+	}
+
+	return location.String()
+}
+
+func (locationValue) Set(s string) error {
+	if s == "" {
+		location = nil
+
+		return nil
+	}
+
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return err
+	}
+	location = loc
+
+	return nil
+}
+
+func (locationValue) Type() string {
+	return "zone"
+}
+
+// addScheduleFlags adds the flags that affect how #freeblock: schedules are checked to cmd.
+func addScheduleFlags(cmd *cobra.Command) {
+	cmd.Flags().Var(
+		locationValue{}, "timezone",
+		"Check schedules in this time zone (e.g. America/Denver) unless they name their own.")
+}
+
+// fileSchedules holds the parts of a hosts file that affect the schedules of all of its lines.
+type fileSchedules struct {
+	named      hosts.Schedules
+	exceptions []hosts.DateRange
+}
+
+// readSchedules reads the named schedules and exceptions from f.
+func readSchedules(f *hosts.File) (*fileSchedules, error) {
+	named, err := f.Schedules()
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := f.Exceptions()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSchedules{named, exceptions}, nil
+}
+
+// blockingWindow returns the window of the entry's schedule that disallows unblocking it at now, if
+// there is one. No window applies on a day covered by an exception or vacation.
+func (s *fileSchedules) blockingWindow(
+	e *hosts.Entry, now time.Time,
+) (w hosts.Window, forbidden bool, err error) {
+	schedule, err := e.Schedule(s.named)
+	if err != nil {
+		return w, false, err
+	}
+
+	now = scheduleTime(now)
+	for _, r := range s.exceptions {
+		if r.Contains(now) {
+			return w, false, nil
+		}
+	}
+
+	w, forbidden = schedule.Active(now)
+
+	return w, forbidden, nil
+}
+
+// nextChange returns the first time after now at which a window of one of the schedules in f starts
+// or ends, or at which the day changes if there are exceptions. Lines with malformed directives are
+// ignored. ok is false if nothing will ever change.
+func (s *fileSchedules) nextChange(f *hosts.File, now time.Time) (next time.Time, ok bool) {
+	now = scheduleTime(now)
+
+	earliest := func(c time.Time) {
+		if next.IsZero() || c.Before(next) {
+			next = c
+		}
+	}
+
+	if len(s.exceptions) != 0 {
+		earliest(nextMidnight(now))
+	}
+
+	for _, e := range f.Hosts() {
+		schedule, err := e.Schedule(s.named)
+		if err != nil {
+			continue
+		}
+		if c, ok := schedule.NextChange(now); ok {
+			earliest(c)
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// maxOpenSteps limits how many window boundaries nextOpen looks through.
+const maxOpenSteps = 100
+
+// nextOpen returns the first time at or after now at which the entry's schedule allows unblocking
+// it. ok is false if that doesn't happen within the next maxOpenSteps window boundaries.
+func (s *fileSchedules) nextOpen(e *hosts.Entry, now time.Time) (open time.Time, ok bool) {
+	schedule, err := e.Schedule(s.named)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	t := scheduleTime(now)
+	for i := 0; i < maxOpenSteps; i++ {
+		if _, forbidden, _ := s.blockingWindow(e, t); !forbidden {
+			return t, true
+		}
+
+		next, hasNext := schedule.NextChange(t)
+		if len(s.exceptions) != 0 {
+			// An exception might start at midnight.
+			if midnight := nextMidnight(t); !hasNext || midnight.Before(next) {
+				next, hasNext = midnight, true
+			}
+		}
+		if !hasNext {
+			return time.Time{}, false
+		}
+		t = next
+	}
+
+	return time.Time{}, false
+}
+
+// nextMidnight returns the start of the day after t.
+func nextMidnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+// scheduleTime returns now in the time zone used for schedules.
+func scheduleTime(now time.Time) time.Time {
+	if location == nil {
+		return now
+	}
+
+	return now.In(location)
+}
@@ -0,0 +1,106 @@
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/services"
+)
+
+var (
+	serviceNames []string
+	servicesFile string
+)
+
+// addServiceFlags adds the flags for naming whole services instead of domains to cmd.
+func addServiceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(
+		&serviceNames, "service", nil,
+		"Include all of the domains of a service, like reddit or youtube. Can be repeated.")
+	cmd.Flags().StringVar(
+		&servicesFile, "services-file", "",
+		"A file that overrides or extends the built-in services. (default "+
+			"\"freeblock/services\" in the user config directory, if it exists)")
+}
+
+// domainArgs requires at least one domain, unless --service is given.
+func domainArgs(_ *cobra.Command, args []string) error {
+	if len(args) == 0 && len(serviceNames) == 0 {
+		return errors.New("requires at least one domain or --service")
+	}
+
+	return nil
+}
+
+// withServices returns the domains followed by the domains of each service given with --service.
+func withServices(domains []string) ([]string, error) {
+	if len(serviceNames) == 0 {
+		return domains, nil
+	}
+
+	if useRegex {
+		return nil, errors.New("--service can't be used with --regex")
+	}
+
+	known, err := loadServices()
+	if err != nil {
+		return nil, err
+	}
+
+	out := append([]string(nil), domains...)
+	for _, name := range serviceNames {
+		serviceDomains, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown service %q (known services: %s)", name, strings.Join(known.Names(), ", "))
+		}
+		for _, domain := range serviceDomains {
+			if !contains(out, domain) {
+				out = append(out, domain)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// loadServices returns the built-in services along with any changes from the services file. It's
+// fine for the default services file not to exist, but not one given with --services-file.
+func loadServices() (services.Services, error) {
+	s := services.Default()
+
+	path := servicesFile
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			// Without a config directory there is no default services file.
+			return s, nil
+		}
+		path = filepath.Join(dir, "freeblock", "services")
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && servicesFile == "" {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read services: %w", err)
+	}
+
+	err = s.Read(bytes.NewReader(b))
+	var parseErr *services.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &ErrParse{File: path, Line: parseErr.Line, Err: parseErr.Err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read services: %w", err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,95 @@
+package cmds_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+	"github.com/kylrth/freeblock/pkg/services"
+)
+
+//nolint:paralleltest // This test modifies package state.
+func TestWithServices(t *testing.T) {
+	servicesFile := filepath.Join(t.TempDir(), "services")
+	writeString(t, servicesFile, `+reddit old.reddit.com
+mysite a.example.com b.example.com
+`)
+	defer cmds.SetServices(servicesFile, "reddit", "mysite")()
+
+	got, err := cmds.WithServices([]string{"example.com", "redd.it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"example.com", "redd.it"}
+	for _, domain := range services.Default()["reddit"] {
+		if domain != "redd.it" {
+			want = append(want, domain)
+		}
+	}
+	want = append(want, "old.reddit.com", "a.example.com", "b.example.com")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("unexpected domains (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestWithServices_errors(t *testing.T) {
+	dir := t.TempDir()
+	badFile := filepath.Join(dir, "bad")
+	writeString(t, badFile, "mysite a.example.com\nempty\n")
+
+	defer cmds.SetServices(filepath.Join(dir, "missing"), "reddit")()
+	_, err := cmds.WithServices(nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing services file to be an error, got %v", err)
+	}
+
+	cmds.SetServices(badFile, "mysite")
+	_, err = cmds.WithServices(nil)
+	var parseErr *cmds.ErrParse
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+		t.Errorf("expected a parse error on line 2, got %v", err)
+	}
+
+	cmds.SetServices("", "nosuchservice")
+	_, err = cmds.WithServices(nil)
+	if err == nil {
+		t.Error("expected an error for an unknown service")
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlockCmd_service(t *testing.T) {
+	dir := t.TempDir()
+	hostsFile := filepath.Join(dir, "hosts")
+	servicesFile := filepath.Join(dir, "services")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n")
+	writeString(t, servicesFile, "mysite a.example.com b.example.com\n")
+	defer cmds.SetServices("")()
+
+	cmds.BlockCmd.SetArgs([]string{
+		"--hosts-file", hostsFile, "--services-file", servicesFile, "--service", "mysite",
+	})
+	if err := cmds.BlockCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `127.0.0.1 localhost
+0.0.0.0 a.example.com
+:: a.example.com
+0.0.0.0 b.example.com
+:: b.example.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
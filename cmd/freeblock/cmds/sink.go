@@ -0,0 +1,124 @@
+package cmds
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// sinkFlag is set with --sink.
+var sinkFlag string
+
+// addSinkFlag adds the flag for choosing the address that blocked domains resolve to to cmd.
+func addSinkFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&sinkFlag, "sink", "",
+		"The address that blocked domains resolve to, with an optional IPv6 address after a comma\n"+
+			"(e.g. 127.0.0.1,::1). Overrides any #freeblock:sink line in the hosts file. (default\n"+
+			"0.0.0.0,::)")
+}
+
+// sinks are the addresses that blocked domains resolve to.
+type sinks struct {
+	v4, v6 string
+}
+
+var defaultSinks = sinks{blockedIP, blockedIPv6}
+
+// fileSinks returns the sinks for f. They're given with --sink, or else on a #freeblock:sink line
+// in f. A missing IPv4 or IPv6 address defaults to a loopback address if the other one is a
+// loopback address, and to 0.0.0.0 or :: otherwise.
+func fileSinks(f *hosts.File) (sinks, error) {
+	var (
+		v4, v6 string
+		err    error
+	)
+	if sinkFlag != "" {
+		v4, v6, err = hosts.ParseSink(sinkFlag)
+		if err != nil {
+			return sinks{}, fmt.Errorf("invalid --sink: %w", err)
+		}
+	} else {
+		v4, v6, err = f.Sink()
+		if err != nil {
+			return sinks{}, err
+		}
+	}
+
+	s := defaultSinks
+	switch {
+	case v4 != "" && v6 != "":
+		s = sinks{v4, v6}
+	case v4 != "":
+		s.v4 = v4
+		if net.ParseIP(v4).IsLoopback() {
+			s.v6 = "::1"
+		}
+	case v6 != "":
+		s.v6 = v6
+		if net.ParseIP(v6).IsLoopback() {
+			s.v4 = "127.0.0.1"
+		}
+	}
+
+	return s, nil
+}
+
+// fileSinksOrDefault is like fileSinks, but returns the default sinks if they can't be read.
+func fileSinksOrDefault(f *hosts.File) sinks {
+	s, err := fileSinks(f)
+	if err != nil {
+		return defaultSinks
+	}
+
+	return s
+}
+
+// forIP returns the sink for a host line with the address ip, which is the IPv6 sink for IPv6
+// lines and the IPv4 sink for the others.
+func (s sinks) forIP(ip string) string {
+	if isIPv6(ip) {
+		return s.v6
+	}
+
+	return s.v4
+}
+
+// blocks returns whether the host line is blocked. Lines pointing to the default sinks always
+// count, so that lines blocked before the sinks were changed are still recognized. Other sinks like
+// 127.0.0.1 are also used by lines added by hand, so a line pointing to one of them only counts if
+// freeblock manages it. A line with the blocked marker counts wherever it points.
+func (s sinks) blocks(e *hosts.Entry) bool {
+	switch {
+	case isDefaultSink(e.IP), e.Marked():
+		return true
+	case e.IP == s.v4 || e.IP == s.v6:
+		return managed(e)
+	}
+
+	return false
+}
+
+// isDefaultSink returns whether ip is 0.0.0.0 or ::, which nothing but blocked lines points to.
+func isDefaultSink(ip string) bool {
+	return ip == blockedIP || ip == blockedIPv6
+}
+
+// managed returns whether freeblock manages the host line, because it has a saved address, a
+// directive or the blocked marker.
+func managed(e *hosts.Entry) bool {
+	_, _, saved := savedIP(e.Comment)
+
+	return saved || e.Marked() || len(e.Directives()) != 0
+}
+
+// markBlocked adds the blocked marker to a line that was just blocked, if it points to a sink that
+// lines added by hand also use and freeblock wouldn't otherwise know that it manages the line.
+func markBlocked(e *hosts.Entry) {
+	if !isDefaultSink(e.IP) && !managed(e) {
+		e.Mark()
+	}
+}
@@ -0,0 +1,126 @@
+package cmds_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+func TestBlock_sink(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.1 myapp.test
+1.2.3.4 reddit.com
+0.0.0.0 example.com
+`)
+
+	if _, err := cmds.Block([]string{"reddit.com", "example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines blocked with the default sinks are moved to the new ones without saving 0.0.0.0. Lines
+	// without a saved address are marked, so that they can be told apart from the loopback lines
+	// added by hand.
+	checkFile(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.1 myapp.test
+127.0.0.1 reddit.com # 1.2.3.4
+127.0.0.1 example.com #freeblock:blocked
+::1 reddit.com #freeblock:blocked
+::1 example.com #freeblock:blocked
+`)
+
+	statuses, err := cmds.Status(nil, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 4 {
+		t.Errorf("expected 4 blocked lines, got %+v", statuses)
+	}
+	for _, s := range statuses {
+		if s.State != cmds.StateBlocked {
+			t.Errorf("expected %s on line %d to be blocked, got %v", s.Domain, s.Line, s.State)
+		}
+	}
+
+	// The lines added by hand aren't blocked, so they're left alone.
+	r, err := cmds.Unblock(
+		[]string{"reddit.com", "example.com", "localhost", "myapp.test"},
+		hostsFile, MockNower{time.Now()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"localhost", "myapp.test"}, r.Skipped); diff != "" {
+		t.Error("unexpected skipped domains (-want +got):\n" + diff)
+	}
+
+	checkFile(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.1 myapp.test
+1.2.3.4 reddit.com
+#127.0.0.1 example.com #freeblock:blocked
+#::1 reddit.com #freeblock:blocked
+#::1 example.com #freeblock:blocked
+`)
+
+	// Blocking the lines again keeps them marked.
+	if _, err = cmds.Block([]string{"example.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.1 myapp.test
+1.2.3.4 reddit.com
+127.0.0.1 example.com #freeblock:blocked
+#::1 reddit.com #freeblock:blocked
+::1 example.com #freeblock:blocked
+`)
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_sinkFlag(t *testing.T) {
+	defer cmds.SetSink("127.0.0.2,::")()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "#freeblock:sink 127.0.0.1\n127.0.0.1 localhost\n")
+
+	if _, err := cmds.Block([]string{"reddit.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flag overrides the sink line.
+	checkFile(t, hostsFile, `#freeblock:sink 127.0.0.1
+127.0.0.1 localhost
+127.0.0.2 reddit.com #freeblock:blocked
+:: reddit.com
+`)
+}
+
+func TestBlock_badSink(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = "#freeblock:sink localhost\n"
+	writeString(t, hostsFile, content)
+
+	_, err := cmds.Block([]string{"reddit.com"}, hostsFile)
+
+	var parseErr *hosts.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 1 {
+		t.Errorf("expected a parse error on line 1, got %v", err)
+	}
+	if code := cmds.ExitCode(err); code != cmds.ExitParse {
+		t.Errorf("expected exit code %d, got %d", cmds.ExitParse, code)
+	}
+	checkFile(t, hostsFile, content)
+}
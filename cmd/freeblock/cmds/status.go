@@ -0,0 +1,206 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// StatusCmd is a command that lists the domains managed by freeblock.
+var StatusCmd = &cobra.Command{
+	Use:     "status [DOMAIN...]",
+	Aliases: []string{"list"},
+	Short:   "list blocked and unblocked domains",
+	Long: `List the domains managed by freeblock, or only the given domains.
+
+A host line is managed by freeblock if its address is a sink address (0.0.0.0 or
+:: unless changed with --sink or a #freeblock:sink line), if it has an original
+address saved in a comment, or if it has a #freeblock: directive. For each line
+of each domain, the state is one of:
+
+  blocked    the domain resolves to a sink address
+  unblocked  the domain resolves to another address
+  commented  the line is commented out
+
+The time range from a #freeblock: directive is shown, along with whether it
+currently forbids unblocking the domain.
+
+Domains may be glob patterns like '*.reddit.com', or regular expressions with
+--regex. A pattern also lists matching lines that aren't managed by freeblock
+yet, showing which lines 'block' or 'unblock' would change with that pattern.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses, err := Status(args, hostsFile, DefaultNower{})
+		if output == outputJSON {
+			r := statusReport{Domains: statuses}
+			if r.Domains == nil {
+				r.Domains = []DomainStatus{}
+			}
+			r.fail(err)
+			exitWithJSON(r, r.ExitCode)
+
+			return
+		}
+		exitOnError(err)
+
+		exitOnError(printStatus(os.Stdout, statuses))
+	},
+}
+
+func init() {
+	addFileFlags(StatusCmd)
+	addSinkFlag(StatusCmd)
+	addOutputFlag(StatusCmd)
+	addScheduleFlags(StatusCmd)
+	addPatternFlag(StatusCmd)
+}
+
+// The states of a domain.
+const (
+	StateBlocked   = "blocked"
+	StateUnblocked = "unblocked"
+	StateCommented = "commented"
+)
+
+// DomainStatus describes a domain managed by freeblock.
+type DomainStatus struct {
+	Domain string `json:"domain"`
+	// Line is the line number of the host line in the hosts file.
+	Line  int    `json:"line"`
+	State string `json:"state"`
+	// OriginalIP is the address saved by 'block', if any.
+	OriginalIP string `json:"original_ip"`
+	// Schedule holds the values of the #freeblock: directives on the line.
+	Schedule []string `json:"schedule"`
+	// Forbidden is true if the schedule currently disallows unblocking the domain.
+	Forbidden bool `json:"forbidden"`
+	// ScheduleError is set if the schedule can't be parsed.
+	ScheduleError string `json:"schedule_error,omitempty"`
+}
+
+// statusReport is printed by the status command with --output json.
+type statusReport struct {
+	Domains []DomainStatus `json:"domains"`
+
+	reportError
+}
+
+// Status returns the status of the domains managed by freeblock in the hostsFile. If domains is not
+// empty, only those domains are included. Domains may be patterns, which also select matching lines
+// that aren't managed by freeblock yet, so that the lines a pattern would affect can be checked.
+func Status(domains []string, hostsFile string, nower Nower) ([]DomainStatus, error) {
+	sel, err := newSelector(domains)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := readFile(hostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return statusFile(f, sel, nower)
+}
+
+func statusFile(f *hosts.File, sel *selector, nower Nower) ([]DomainStatus, error) {
+	var out []DomainStatus
+
+	sink, sinkErr := fileSinks(f)
+	if sinkErr != nil {
+		return nil, sinkErr
+	}
+	now := nower.Now()
+	schedules, schedulesErr := readSchedules(f)
+
+	for _, e := range f.Hosts() {
+		originalIP, _, _ := savedIP(e.Comment)
+		schedule := e.Directives()
+		listed := sink.blocks(e) || managed(e) ||
+			anyMatch(e.Hostnames, sel.byName) || anyMatch(e.Hostnames, sel.byPattern)
+		if !listed {
+			continue
+		}
+
+		state := StateUnblocked
+		switch {
+		case e.Disabled:
+			state = StateCommented
+		case sink.blocks(e):
+			state = StateBlocked
+		}
+
+		// Unblock refuses to use any schedule if the header lines of the file are broken.
+		forbidden, err := false, schedulesErr
+		if schedules != nil {
+			_, forbidden, err = schedules.blockingWindow(e, now)
+		}
+		var scheduleErr string
+		if err != nil {
+			scheduleErr = err.Error()
+		}
+
+		for _, hostname := range e.Hostnames {
+			if !sel.all() && !sel.byName(hostname) && !sel.byPattern(hostname) {
+				continue
+			}
+
+			out = append(out, DomainStatus{
+				Domain:        hostname,
+				Line:          e.Num,
+				State:         state,
+				OriginalIP:    originalIP,
+				Schedule:      append([]string{}, schedule...),
+				Forbidden:     forbidden,
+				ScheduleError: scheduleErr,
+			})
+		}
+	}
+
+	return out, nil
+}
+
+func printStatus(w io.Writer, statuses []DomainStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "DOMAIN\tLINE\tSTATE\tORIGINAL IP\tSCHEDULE\tUNBLOCK")
+	for _, s := range statuses {
+		unblock := "allowed"
+		switch {
+		case s.ScheduleError != "":
+			unblock = "invalid schedule"
+		case s.Forbidden:
+			unblock = "forbidden"
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			s.Domain, s.Line, s.State, dash(s.OriginalIP), dash(strings.Join(s.Schedule, " ")), unblock)
+	}
+
+	return tw.Flush()
+}
+
+// anyMatch returns whether match is true for any of the hostnames.
+func anyMatch(hostnames []string, match func(string) bool) bool {
+	for _, h := range hostnames {
+		if match(h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dash returns "-" in place of an empty string.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,105 @@
+package cmds_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `127.0.0.1 localhost
+0.0.0.0   www.reddit.com reddit.com  #freeblock:07-08 # comments
+0.0.0.0   internal.example.com  #freeblock:06-07 # 1.2.3.4
+#0.0.0.0   example.com
+1.2.3.4   github.com # 4.3.2.1 is not a saved address
+5.6.7.8   google.com #freeblock:06-07
+0.0.0.0   twitter.com #freeblock:06:30-6:45
+0.0.0.0   facebook.com #freeblock:6-7pm
+`)
+
+	now := time.Date(2021, 10, 4, 6, 30, 0, 0, time.Local)
+
+	got, err := cmds.Status(nil, hostsFile, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cmds.DomainStatus{
+		{
+			Domain: "www.reddit.com", Line: 2, State: cmds.StateBlocked,
+			Schedule: []string{"07-08"},
+		},
+		{
+			Domain: "reddit.com", Line: 2, State: cmds.StateBlocked,
+			Schedule: []string{"07-08"},
+		},
+		{
+			Domain: "internal.example.com", Line: 3, State: cmds.StateBlocked,
+			OriginalIP: "1.2.3.4", Schedule: []string{"06-07"}, Forbidden: true,
+		},
+		{Domain: "example.com", Line: 4, State: cmds.StateCommented},
+		{
+			Domain: "google.com", Line: 6, State: cmds.StateUnblocked,
+			Schedule: []string{"06-07"}, Forbidden: true,
+		},
+		{
+			Domain: "twitter.com", Line: 7, State: cmds.StateBlocked,
+			Schedule: []string{"06:30-6:45"}, Forbidden: true,
+		},
+		{
+			Domain: "facebook.com", Line: 8, State: cmds.StateBlocked,
+			Schedule:      []string{"6-7pm"},
+			ScheduleError: `line 8: invalid directive "6-7pm": invalid time of day "7pm"`,
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+
+	// Only the requested domains should be listed, including those freeblock doesn't manage.
+	got, err = cmds.Status(
+		[]string{"example.com", "reddit.com", "localhost"}, hostsFile, MockNower{now},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []cmds.DomainStatus{
+		{Domain: "localhost", Line: 1, State: cmds.StateUnblocked}, want[1], want[3],
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+}
+
+func TestStatus_namedSchedule(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#freeblock:schedule night = 22-06
+#freeblock:schedule night = 23-06
+0.0.0.0 reddit.com #freeblock:schedule=night
+`)
+
+	got, err := cmds.Status(nil, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cmds.DomainStatus{{
+		Domain: "reddit.com", Line: 3, State: cmds.StateBlocked,
+		Schedule: []string{"schedule=night"},
+		ScheduleError: `line 2: invalid directive "schedule night = 23-06":` +
+			` schedule "night" is defined more than once`,
+	}}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Error("unexpected output (-want +got):\n" + diff)
+	}
+}
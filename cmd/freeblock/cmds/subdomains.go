@@ -0,0 +1,65 @@
+package cmds
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultSubdomains are the subdomains added by --with-subdomains unless --subdomains is given.
+var defaultSubdomains = []string{
+	"www", "m", "mobile", "old", "new", "api", "app", "amp", "static", "cdn", "i", "img", "video",
+}
+
+var (
+	withSubdomains bool
+	subdomains     = defaultSubdomains
+)
+
+// addSubdomainFlags adds the flags for including subdomains of each domain to cmd.
+func addSubdomainFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&withSubdomains, "with-subdomains", false,
+		"Include common subdomains of each domain (see --subdomains).")
+	cmd.Flags().StringSliceVar(
+		&subdomains, "subdomains", defaultSubdomains,
+		"The subdomains included by --with-subdomains.")
+}
+
+// domainFamilies returns a family of hostnames for each domain. With --with-subdomains, the family
+// is the domain followed by its subdomains. Otherwise it's just the domain.
+func domainFamilies(domains []string) [][]string {
+	families := make([][]string, len(domains))
+
+	for i, domain := range domains {
+		families[i] = []string{domain}
+		if !withSubdomains {
+			continue
+		}
+
+		for _, sub := range subdomains {
+			sub = strings.Trim(sub, ".")
+			if sub == "" || strings.HasPrefix(domain, sub+".") {
+				continue
+			}
+			families[i] = append(families[i], sub+"."+domain)
+		}
+	}
+
+	return families
+}
+
+// flatten returns the hostnames of all of the families, without duplicates.
+func flatten(families [][]string) []string {
+	var out []string
+
+	for _, family := range families {
+		for _, domain := range family {
+			if !contains(out, domain) {
+				out = append(out, domain)
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,124 @@
+package cmds_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_withSubdomains(t *testing.T) {
+	defer cmds.SetSubdomains("www", "old")()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = `127.0.0.1 localhost
+1.2.3.4 www.reddit.com
+`
+	writeString(t, hostsFile, content)
+
+	r, err := cmds.Block([]string{"reddit.com", "old.example.com"}, hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChanged := []string{
+		"reddit.com", "www.reddit.com", "old.reddit.com", "old.example.com", "www.old.example.com",
+	}
+	if diff := cmp.Diff(wantChanged, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `127.0.0.1 localhost
+0.0.0.0 www.reddit.com # 1.2.3.4
+0.0.0.0 reddit.com old.reddit.com
+:: reddit.com www.reddit.com old.reddit.com
+0.0.0.0 old.example.com www.old.example.com
+:: old.example.com www.old.example.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+
+	_, err = cmds.Unblock([]string{"reddit.com", "old.example.com"}, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `127.0.0.1 localhost
+1.2.3.4 www.reddit.com
+#0.0.0.0 reddit.com old.reddit.com
+#:: reddit.com www.reddit.com old.reddit.com
+#0.0.0.0 old.example.com www.old.example.com
+#:: old.example.com www.old.example.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestBlock_longFamily(t *testing.T) {
+	defer cmds.SetSubdomains("www", "m", "old", "new", "i", "v", "api", "gql", "out", "static")()
+	defer cmds.SetIPv6(false)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n")
+
+	_, err := cmds.Block([]string{"reddit.com"}, hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Windows ignores the hostnames after the ninth on a line.
+	checkFile(t, hostsFile, "127.0.0.1 localhost\n"+
+		"0.0.0.0 reddit.com www.reddit.com m.reddit.com old.reddit.com new.reddit.com"+
+		" i.reddit.com v.reddit.com api.reddit.com gql.reddit.com\n"+
+		"0.0.0.0 out.reddit.com static.reddit.com\n")
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestUnblock_subdomainFamily(t *testing.T) {
+	defer cmds.SetSubdomains("www")()
+	defer cmds.SetPartial(true)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = `0.0.0.0 reddit.com
+0.0.0.0 www.reddit.com #freeblock:09-17
+0.0.0.0 example.com
+`
+	writeString(t, hostsFile, content)
+
+	now := time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local)
+
+	// reddit.com is left alone because www.reddit.com is refused, even in partial mode.
+	_, err := cmds.Unblock([]string{"reddit.com", "example.com"}, hostsFile, MockNower{now})
+	var as *cmds.ErrRefused
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrRefused, got %v", err)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `0.0.0.0 reddit.com
+0.0.0.0 www.reddit.com #freeblock:09-17
+#0.0.0.0 example.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
@@ -1,37 +1,62 @@
 package cmds
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
 )
 
 // UnblockCmd is a command that unblocks domains in the hosts file.
 var UnblockCmd = &cobra.Command{
-	Use:   "unblock DOMAIN [DOMAIN...]",
+	Use:   "unblock [DOMAIN...]",
 	Short: "unblock domains",
-	Long: `Unblock domains by commenting out 0.0.0.0 entries from the hosts file for each
-domain.
+	Long: `Unblock domains by commenting out 0.0.0.0 and :: entries from the hosts file for
+each domain.
 
 For blocked hosts with a comment that has another IP address, the domain is
 reverted back to to that IP address and the comment is deleted.
+
+If time ranges refuse some of the domains, all of the refused domains are
+listed and nothing is unblocked, unless --partial is given.
+
+With --with-subdomains, common subdomains like www.DOMAIN are unblocked too. A
+domain and its subdomains are treated as a unit, so if a time range refuses one
+of them, none of them are unblocked, even with --partial.
+
+With --service, all of the domains of a service like reddit or youtube are
+unblocked. See 'freeblock block --help' for how to change the list of services.
+
+A domain containing '*', '?' or '[' is a glob pattern, like '*.reddit.com'. It
+unblocks every hostname in the file that matches it. With --regex, each domain
+is a regular expression matched against the whole hostname instead.
 `,
-	Args: cobra.MinimumNArgs(1),
+	Args: domainArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := Unblock(args, hostsFile, DefaultNower{}); err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+		domains, err := withServices(args)
+		if err != nil {
+			exitWithReport(nil, err)
+
+			return
 		}
+		exitWithReport(Unblock(domains, hostsFile, DefaultNower{}))
 	},
 }
 
 func init() {
-	UnblockCmd.Flags().StringVar(
-		&hostsFile, "hosts-file", defaultHostsFile, "Change the default hosts file.")
+	addFileFlags(UnblockCmd)
+	addSinkFlag(UnblockCmd)
+	addOutputFlag(UnblockCmd)
+	addScheduleFlags(UnblockCmd)
+	addPartialFlag(UnblockCmd)
+	addSubdomainFlags(UnblockCmd)
+	addServiceFlags(UnblockCmd)
+	addPatternFlag(UnblockCmd)
 }
 
 // Nower is something that can return the current time. Used for mocking during tests.
@@ -46,76 +71,280 @@ func (DefaultNower) Now() time.Time {
 	return time.Now()
 }
 
-// Unblock blocks the domains in the hostsFile.
-func Unblock(domains []string, hostsFile string, nower Nower) error {
-	lines, err := readLines(hostsFile)
-	if err != nil {
+// Clock is a Nower that can also wait for time to pass. Used for mocking during tests.
+type Clock interface {
+	Nower
+	After(d time.Duration) <-chan time.Time
+}
+
+// DefaultClock implements Clock with the time package.
+type DefaultClock struct {
+	DefaultNower
+}
+
+func (DefaultClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Unblock unblocks the domains in the hostsFile.
+func Unblock(domains []string, hostsFile string, nower Nower) (*Report, error) {
+	var (
+		changes   []change
+		refused   error
+		families  [][]string
+		unmatched []string
+	)
+
+	err := editFile(hostsFile, nower, func(f *hosts.File, _ *journal) error {
+		var err error
+		families, unmatched, err = resolveFamilies(f, domains)
+		if err != nil {
+			return err
+		}
+		changes, err = unblockFile(f, families, nower)
+		refused, err = splitRefused(err)
+
 		return err
+	})
+	if err != nil && !errors.Is(err, ErrChangesPending) {
+		if refusals(err) == nil {
+			return newReport(nil, nil), err
+		}
+
+		// Nothing was changed, so the domains that weren't refused were skipped.
+		return newReport(append(flatten(families), unmatched...), nil), err
 	}
+	if refused != nil {
+		err = refused
+	}
+
+	return newReport(append(flatten(families), unmatched...), changes), err
+}
 
-	// Modify the lines in place.
-	for i, line := range lines {
-		hostnames := line.Hostnames()
-		if len(hostnames) == 0 {
+// partial is set with --partial.
+var partial bool
+
+// addPartialFlag adds the flag for unblocking the allowed domains when others are refused to cmd.
+func addPartialFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&partial, "partial", false,
+		"Unblock the domains that are allowed even if time ranges refuse others, and list the\n"+
+			"refused ones. The exit code is still 3 if any were refused.")
+}
+
+// splitRefused separates an *ErrRefused returned by unblockFile from other errors. In partial
+// mode, the refusal is returned separately so that the changes to the allowed domains are written
+// anyway. Otherwise it's left in err, so that nothing is written.
+func splitRefused(err error) (refused, rest error) {
+	var r *ErrRefused
+	if !partial || !errors.As(err, &r) {
+		return nil, err
+	}
+
+	return err, nil
+}
+
+// change is a modification to a host line, recorded so that it can be undone later.
+type change struct {
+	Domain string     `json:"domain"`
+	Before hosts.Line `json:"before"`
+	After  hosts.Line `json:"after"`
+}
+
+// unblockFile unblocks the domains in each family in f, returning the changes made. If time ranges
+// don't allow some of the domains to be unblocked right now, an *ErrRefused listing all of them is
+// returned along with the changes made to the others. The lines of a family with a refused domain
+// are all left alone.
+func unblockFile(f *hosts.File, families [][]string, nower Nower) ([]change, error) {
+	var (
+		changes []change
+		refused ErrRefused
+		domains = flatten(families)
+	)
+
+	schedules, schedulesErr := readSchedules(f)
+	if schedulesErr != nil {
+		return nil, schedulesErr
+	}
+	s, sinksErr := fileSinks(f)
+	if sinksErr != nil {
+		return nil, sinksErr
+	}
+	now := nower.Now()
+
+	// Find all of the refused domains first.
+	for _, e := range f.Hosts() {
+		if !containsAny(domains, e.Hostnames) {
 			continue
 		}
 
-		// See if this line refers to one or more of the domains we want to block.
-		for _, hostname := range hostnames {
-			if !contains(domains, hostname) {
-				continue
+		// See if there's a time range we need to respect.
+		w, forbidden, err := schedules.blockingWindow(e, now)
+		if err != nil {
+			return nil, err
+		}
+		if !forbidden {
+			continue
+		}
+		for _, h := range e.Hostnames {
+			if contains(domains, h) {
+				refused.Refusals = append(refused.Refusals,
+					&ErrBlockTiming{e.Num, h, w, scheduleTime(now)})
 			}
+		}
+	}
 
-			// See if there's a time range we need to respect.
-			blockStart, blockEnd := line.Timing()
-			now := nower.Now()
-			if now.Hour() >= blockStart && now.Hour() < blockEnd {
-				return &ErrBlockTiming{i + 1, hostname, blockStart, blockEnd, now}
-			}
+	// Leave the whole family of each refused domain alone.
+	held := make(map[string]bool)
+	for _, family := range families {
+		for _, r := range refused.Refusals {
+			if contains(family, r.Domain) {
+				for _, h := range family {
+					held[h] = true
+				}
 
-			oldIP := line.GetIP()
-			if oldIP != blockedIP {
-				// We don't want to comment this one out, because it's already unblocked.
-				continue
+				break
 			}
+		}
+	}
 
-			// Check for a commented IP address.
-			commentIdx := strings.Index(string(line), commentPrefix) //nolint:gocritic // false positive
-			if commentIdx == -1 {
-				// There's no IP address to revert to, so we'll just comment out the line.
-				line.Comment()
-			} else {
-				commentFields := strings.Fields(string(line)[commentIdx+len(commentPrefix):])
-				if len(commentFields) == 1 && net.ParseIP(commentFields[0]) != nil {
-					// We'll revert to the commented IP address.
-					line.SetIP(commentFields[0])
-					line = line[:commentIdx]
-				} else {
-					// This comment contains something else besides an IP address.
-					line.Comment()
-				}
-			}
-			lines[i] = line
+	// Modify the entries in place.
+	for _, e := range f.Hosts() {
+		// See if this entry refers to one or more of the domains we want to unblock.
+		hostname, ok := firstRequested(e, domains)
+		if !ok || anyHeld(e, held) {
+			continue
+		}
+
+		if c, ok := unblockEntry(e, s); ok {
+			c.Domain = hostname
+			changes = append(changes, c)
+		}
+	}
+
+	if len(refused.Refusals) != 0 {
+		return changes, &refused
+	}
+
+	return changes, nil
+}
+
+// anyHeld returns whether any of the hostnames of the entry are held.
+func anyHeld(e *hosts.Entry, held map[string]bool) bool {
+	for _, h := range e.Hostnames {
+		if held[h] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unblockEntry unblocks the host line, returning the change if there was one. The address saved in
+// the comment is restored if there is one, and otherwise the line is commented out.
+func unblockEntry(e *hosts.Entry, s sinks) (change, bool) {
+	if !s.blocks(e) {
+		// We don't want to comment this one out, because it's already unblocked.
+		return change{}, false
+	}
+
+	before := e.Line()
+
+	// Check for a commented IP address.
+	if ip, rest, ok := savedIP(e.Comment); ok {
+		// We'll revert to the commented IP address.
+		e.IP = ip
+		e.SetComment(rest)
+	} else {
+		// There's no IP address to revert to, so we'll just comment out the line.
+		e.Disabled = true
+	}
+
+	after := e.Line()
+
+	return change{e.Hostnames[0], before, after}, after != before
+}
 
-			break
+// firstRequested returns the first hostname of the entry that is one of the domains.
+func firstRequested(e *hosts.Entry, domains []string) (string, bool) {
+	for _, hostname := range e.Hostnames {
+		if contains(domains, hostname) {
+			return hostname, true
 		}
 	}
 
-	return writeLines(lines, hostsFile)
+	return "", false
+}
+
+// savedIP looks for the original IP address that Block saved at the end of an inline comment. If
+// found, the comment without the IP address is returned as well.
+func savedIP(comment string) (ip, rest string, ok bool) {
+	s := " " + comment
+
+	idx := strings.LastIndex(s, commentPrefix)
+	if idx == -1 {
+		return "", "", false
+	}
+
+	fields := strings.Fields(s[idx+len(commentPrefix):])
+	if len(fields) != 1 || net.ParseIP(fields[0]) == nil {
+		// The last part of the comment contains something else besides an IP address.
+		return "", "", false
+	}
+
+	return fields[0], strings.TrimPrefix(s[:idx], " "), true
 }
 
 // ErrBlockTiming is returned when the hosts file has specified that this domain is not to be
 // unblocked right now.
 type ErrBlockTiming struct {
-	lineNum    int
-	domain     string
-	start, end int
-	now        time.Time
+	LineNum int
+	Domain  string
+	// Window is the window of the line's schedule that disallows unblocking.
+	Window hosts.Window
+	Now    time.Time
 }
 
 func (e *ErrBlockTiming) Error() string {
+	now := e.Now
+
+	var days, zone string
+	if e.Window.Days != 0 && e.Window.Days != hosts.EveryDay {
+		days = " on " + e.Window.Days.String()
+	}
+	if e.Window.Location != nil {
+		now = now.In(e.Window.Location)
+		zone = " (" + e.Window.Location.String() + ")"
+	}
+
 	return fmt.Sprintf(
-		"it's %02d:%02d and line %d of the hosts file disallows unblocking %s from %02d:00 to %02d:00",
-		e.now.Hour(), e.now.Minute(), e.lineNum, e.domain, e.start, e.end,
+		"it's %02d:%02d and line %d of the hosts file disallows unblocking %s from %v to %v%s%s",
+		now.Hour(), now.Minute(), e.LineNum, e.Domain, e.Window.Start, e.Window.End, days, zone,
 	)
 }
+
+// ErrRefused is returned when time ranges in the hosts file don't allow some of the requested
+// domains to be unblocked right now. It lists all of the refused domains. Unwrapping it gives the
+// first refusal.
+type ErrRefused struct {
+	Refusals []*ErrBlockTiming
+}
+
+func (e *ErrRefused) Error() string {
+	if len(e.Refusals) == 1 {
+		return e.Refusals[0].Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d domains can't be unblocked right now:", len(e.Refusals))
+	for _, r := range e.Refusals {
+		b.WriteString("\n\t- ")
+		b.WriteString(r.Error())
+	}
+
+	return b.String()
+}
+
+func (e *ErrRefused) Unwrap() error {
+	return e.Refusals[0]
+}
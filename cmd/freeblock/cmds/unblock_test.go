@@ -1,13 +1,18 @@
 package cmds_test
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+	"github.com/kylrth/freeblock/pkg/hosts"
 )
 
 //nolint:paralleltest // This test modifies package state.
@@ -45,7 +50,7 @@ func TestUnblock_fail(t *testing.T) {
 	}
 
 	// Run Unblock and fail.
-	err = cmds.Unblock(
+	_, err = cmds.Unblock(
 		[]string{"google.com", "example.com", "internal.example.com", "github.com"},
 		hostsFile, MockNower{now},
 	)
@@ -58,6 +63,16 @@ func TestUnblock_fail(t *testing.T) {
 	if diff := cmp.Diff(want, errStr); diff != "" {
 		t.Fatal("unexpected error (-want +got):\n" + diff)
 	}
+	var as *cmds.ErrBlockTiming
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrBlockTiming, got %T", err)
+	}
+	wantErr := &cmds.ErrBlockTiming{
+		LineNum: 1, Domain: "google.com", Window: hosts.Window{Start: 6 * 60, End: 7 * 60}, Now: now,
+	}
+	if diff := cmp.Diff(wantErr, as); diff != "" {
+		t.Fatal("unexpected error fields (-want +got):\n" + diff)
+	}
 
 	// Check the file.
 	checkWantFile(t, hostsFile)
@@ -78,7 +93,7 @@ func TestUnblock_success(t *testing.T) {
 	}
 
 	// Run Unblock.
-	err = cmds.Unblock(
+	_, err = cmds.Unblock(
 		[]string{"google.com", "example.com", "internal.example.com", "github.com"},
 		hostsFile, MockNower{now},
 	)
@@ -90,6 +105,385 @@ func TestUnblock_success(t *testing.T) {
 	checkWantFile(t, hostsFile)
 }
 
+func TestUnblock_userComment(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	const content = "1.2.3.4 www.reddit.com  #freeblock:09-17 # Don't get distracted!\n"
+	writeString(t, hostsFile, content)
+
+	if _, err := cmds.Block([]string{"www.reddit.com"}, hostsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := cmds.Status([]string{"www.reddit.com"}, hostsFile, MockNower{time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) == 0 || statuses[0].OriginalIP != "1.2.3.4" {
+		t.Errorf("expected the original IP to be 1.2.3.4, got %+v", statuses)
+	}
+
+	now := time.Date(2021, 10, 4, 20, 0, 0, 0, time.Local)
+	if _, err = cmds.Unblock([]string{"www.reddit.com"}, hostsFile, MockNower{now}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The IPv4 line gets its address back, and the new IPv6 line is commented out.
+	checkFile(t, hostsFile, content+"#:: www.reddit.com #freeblock:09-17\n")
+}
+
+func TestUnblock_minutes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		now     string
+		wantErr string
+	}{
+		"before": {now: "08:29"},
+		"start": {
+			now: "08:30",
+			wantErr: "it's 08:30 and line 1 of the hosts file disallows unblocking google.com" +
+				" from 08:30 to 12:15",
+		},
+		"inside": {
+			now: "12:14",
+			wantErr: "it's 12:14 and line 1 of the hosts file disallows unblocking google.com" +
+				" from 08:30 to 12:15",
+		},
+		"end": {now: "12:15"},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hostsFile := filepath.Join(t.TempDir(), "hosts")
+			writeString(t, hostsFile, "0.0.0.0 google.com #freeblock:08:30-12:15 # 1.2.3.4\n")
+
+			now, err := time.ParseInLocation("15:04", tc.now, time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cmds.Unblock([]string{"google.com"}, hostsFile, MockNower{now})
+			var errStr string
+			if err != nil {
+				errStr = err.Error()
+			}
+			if diff := cmp.Diff(tc.wantErr, errStr); diff != "" {
+				t.Error("unexpected error (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestUnblock_overnight(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		now     time.Time
+		refused bool
+	}{
+		"evening":         {now: time.Date(2021, 10, 4, 21, 59, 0, 0, time.Local)},
+		"before_midnight": {now: time.Date(2021, 10, 4, 23, 30, 0, 0, time.Local), refused: true},
+		"after_midnight":  {now: time.Date(2021, 10, 5, 0, 30, 0, 0, time.Local), refused: true},
+		"early_morning":   {now: time.Date(2021, 10, 5, 5, 59, 0, 0, time.Local), refused: true},
+		"morning":         {now: time.Date(2021, 10, 5, 6, 0, 0, 0, time.Local)},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hostsFile := filepath.Join(t.TempDir(), "hosts")
+			writeString(t, hostsFile, "0.0.0.0 reddit.com #freeblock:22-06 # 1.2.3.4\n")
+
+			_, err := cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{tc.now})
+			var as *cmds.ErrBlockTiming
+			if refused := errors.As(err, &as); refused != tc.refused {
+				t.Fatalf("expected refused to be %v, got error %v", tc.refused, err)
+			}
+			if err != nil && !tc.refused {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUnblock_weekdays(t *testing.T) {
+	t.Parallel()
+
+	// October 4, 2021 was a Monday.
+	tests := map[string]struct {
+		now     time.Time
+		wantErr string
+	}{
+		"monday": {
+			now: time.Date(2021, 10, 4, 10, 0, 0, 0, time.Local),
+			wantErr: "it's 10:00 and line 1 of the hosts file disallows unblocking reddit.com" +
+				" from 09:00 to 17:00 on mon-fri",
+		},
+		"monday_evening": {now: time.Date(2021, 10, 4, 18, 0, 0, 0, time.Local)},
+		"saturday":       {now: time.Date(2021, 10, 9, 10, 0, 0, 0, time.Local)},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hostsFile := filepath.Join(t.TempDir(), "hosts")
+			writeString(t, hostsFile, "0.0.0.0 reddit.com #freeblock:mon-fri@09-17 # 1.2.3.4\n")
+
+			_, err := cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{tc.now})
+			var errStr string
+			if err != nil {
+				errStr = err.Error()
+			}
+			if diff := cmp.Diff(tc.wantErr, errStr); diff != "" {
+				t.Error("unexpected error (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestUnblock_multipleWindows(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		now     string
+		wantErr string
+	}{
+		"morning": {
+			now: "10:00",
+			wantErr: "it's 10:00 and line 1 of the hosts file disallows unblocking reddit.com" +
+				" from 09:00 to 12:00",
+		},
+		"lunch": {now: "12:30"},
+		"afternoon": {
+			now: "16:59",
+			wantErr: "it's 16:59 and line 1 of the hosts file disallows unblocking reddit.com" +
+				" from 13:00 to 17:00",
+		},
+		"night": {
+			now: "23:00",
+			wantErr: "it's 23:00 and line 1 of the hosts file disallows unblocking reddit.com" +
+				" from 22:00 to 06:00",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			hostsFile := filepath.Join(t.TempDir(), "hosts")
+			writeString(t, hostsFile,
+				"0.0.0.0 reddit.com #freeblock:09-12,13-17 #freeblock:22-06 # 1.2.3.4\n")
+
+			now, err := time.ParseInLocation("15:04", tc.now, time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{now})
+			var errStr string
+			if err != nil {
+				errStr = err.Error()
+			}
+			if diff := cmp.Diff(tc.wantErr, errStr); diff != "" {
+				t.Error("unexpected error (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestUnblock_timezone(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "0.0.0.0 reddit.com #freeblock:09-17@America/Denver # 1.2.3.4\n")
+	fileShouldNotChange(t, hostsFile)
+
+	// This is 09:30 in Denver, since daylight saving time has started.
+	now := time.Date(2021, 3, 15, 15, 30, 0, 0, time.UTC)
+
+	_, err := cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{now})
+	if err == nil {
+		t.Fatal("expected error, didn't get one")
+	}
+	want := "it's 09:30 and line 1 of the hosts file disallows unblocking reddit.com" +
+		" from 09:00 to 17:00 (America/Denver)"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Error("unexpected error (-want +got):\n" + diff)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestUnblock_defaultTimezone(t *testing.T) {
+	restore, err := cmds.SetTimezone("Asia/Tokyo")
+	defer restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0 example.com #freeblock:09-17@UTC # 1.2.3.4
+`)
+
+	// This is 10:00 in UTC and 19:00 in Tokyo.
+	now := time.Date(2021, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	// The line without a zone is checked in Tokyo.
+	_, err = cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The line with its own zone ignores the default.
+	_, err = cmds.Unblock([]string{"example.com"}, hostsFile, MockNower{now})
+	var as *cmds.ErrBlockTiming
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrBlockTiming, got %v", err)
+	}
+
+	// An unknown zone is rejected.
+	_, err = cmds.SetTimezone("Mars/Olympus_Mons")
+	if err == nil {
+		t.Error("expected error for unknown time zone")
+	}
+}
+
+func TestUnblock_namedSchedule(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#freeblock:schedule workhours = mon-fri 09:00-17:30
+0.0.0.0 reddit.com #freeblock:schedule=workhours # 1.2.3.4
+0.0.0.0 example.com #freeblock:schedule=workhours # 5.6.7.8
+`)
+
+	// October 4, 2021 was a Monday.
+	now := time.Date(2021, 10, 4, 17, 15, 0, 0, time.Local)
+
+	_, err := cmds.Unblock([]string{"example.com"}, hostsFile, MockNower{now})
+	if err == nil {
+		t.Fatal("expected error, didn't get one")
+	}
+	want := "it's 17:15 and line 3 of the hosts file disallows unblocking example.com" +
+		" from 09:00 to 17:30 on mon-fri"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Error("unexpected error (-want +got):\n" + diff)
+	}
+
+	_, err = cmds.Unblock([]string{"example.com"}, hostsFile, MockNower{now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnblock_allRefusals(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0 example.com # 5.6.7.8
+0.0.0.0 youtube.com #freeblock:10-11
+`)
+	fileShouldNotChange(t, hostsFile)
+
+	now := time.Date(2021, 10, 4, 10, 30, 0, 0, time.Local)
+
+	r, err := cmds.Unblock(
+		[]string{"reddit.com", "example.com", "youtube.com"}, hostsFile, MockNower{now},
+	)
+	var as *cmds.ErrRefused
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrRefused, got %v", err)
+	}
+	// The refused domains are moved from Skipped to Refused when the report is printed.
+	wantReport := &cmds.Report{
+		Changed: []string{},
+		Skipped: []string{"reddit.com", "example.com", "youtube.com"},
+		Refused: []cmds.Refusal{},
+	}
+	if diff := cmp.Diff(wantReport, r, cmpopts.IgnoreUnexported(cmds.Report{})); diff != "" {
+		t.Error("unexpected report (-want +got):\n" + diff)
+	}
+	want := `2 domains can't be unblocked right now:
+	- it's 10:30 and line 1 of the hosts file disallows unblocking reddit.com from 09:00 to 17:00
+	- it's 10:30 and line 3 of the hosts file disallows unblocking youtube.com from 10:00 to 11:00`
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Error("unexpected error (-want +got):\n" + diff)
+	}
+	if code := cmds.ExitCode(err); code != cmds.ExitRefused {
+		t.Errorf("expected exit code %d, got %d", cmds.ExitRefused, code)
+	}
+}
+
+//nolint:paralleltest // This test modifies package state.
+func TestUnblock_partial(t *testing.T) {
+	defer cmds.SetPartial(true)()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+0.0.0.0 example.com # 5.6.7.8
+0.0.0.0 youtube.com
+`)
+
+	now := time.Date(2021, 10, 4, 10, 30, 0, 0, time.Local)
+
+	r, err := cmds.Unblock(
+		[]string{"reddit.com", "example.com", "youtube.com"}, hostsFile, MockNower{now},
+	)
+	var as *cmds.ErrRefused
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *ErrRefused, got %v", err)
+	}
+	if len(as.Refusals) != 1 || as.Refusals[0].Domain != "reddit.com" {
+		t.Errorf("unexpected refusals %v", as)
+	}
+	if diff := cmp.Diff([]string{"example.com", "youtube.com"}, r.Changed); diff != "" {
+		t.Error("unexpected changed domains (-want +got):\n" + diff)
+	}
+
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+5.6.7.8 example.com
+#0.0.0.0 youtube.com
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+func TestUnblock_badDirective(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "127.0.0.1 localhost\n0.0.0.0 google.com #freeblock:08:3-12 # 1.2.3.4\n")
+	fileShouldNotChange(t, hostsFile)
+
+	_, err := cmds.Unblock([]string{"google.com"}, hostsFile, MockNower{time.Now()})
+	var as *hosts.ParseError
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *hosts.ParseError, got %v", err)
+	}
+	if as.Line != 2 || as.Directive != "08:3-12" {
+		t.Errorf("unexpected error %v", as)
+	}
+	if code := cmds.ExitCode(err); code != cmds.ExitParse {
+		t.Errorf("expected exit code %d, got %d", cmds.ExitParse, code)
+	}
+}
+
 // MockNower is a Nower that always returns the same time.Time.
 type MockNower struct {
 	T time.Time
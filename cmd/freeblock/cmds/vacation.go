@@ -0,0 +1,94 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+// VacationCmd is a command that suspends the schedules in the hosts file until a date.
+var VacationCmd = &cobra.Command{
+	Use:   "vacation --until DATE",
+	Short: "suspend #freeblock: schedules until a date",
+	Long: `Suspend the time ranges from #freeblock: directives until the end of the given
+date (YYYY-MM-DD), so that domains can be unblocked at any time.
+
+The vacation is recorded in the hosts file as a line like this:
+
+  #freeblock:vacation 2026-12-31
+
+Running the command again replaces the date. Use --end to end the vacation
+early. To skip single days or ranges of days instead, add a line like this to
+the hosts file:
+
+  #freeblock:except 2026-12-24..2026-12-26 2027-01-01
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if vacationEnd {
+			ended, err := EndVacation(hostsFile)
+			exitOnError(err)
+			if !ended {
+				fmt.Fprintln(os.Stderr, "No vacation to end.")
+			}
+
+			return
+		}
+
+		if vacationUntil == "" {
+			exitOnError(errors.New("either --until or --end is required"))
+		}
+		last, err := time.ParseInLocation(hosts.DateLayout, vacationUntil, time.UTC)
+		if err != nil {
+			exitOnError(fmt.Errorf("invalid date %q: expected YYYY-MM-DD", vacationUntil))
+		}
+		exitOnError(Vacation(hostsFile, last, DefaultNower{}))
+	},
+}
+
+var (
+	vacationUntil string
+	vacationEnd   bool
+)
+
+func init() {
+	addFileFlags(VacationCmd)
+	addScheduleFlags(VacationCmd)
+	VacationCmd.Flags().StringVar(
+		&vacationUntil, "until", "", "Suspend schedules until the end of this date (YYYY-MM-DD).")
+	VacationCmd.Flags().BoolVar(&vacationEnd, "end", false, "End the current vacation.")
+}
+
+// Vacation suspends the schedules in the hostsFile until the end of the date last, which must not
+// be before today.
+func Vacation(hostsFile string, last time.Time, nower Nower) error {
+	now := scheduleTime(nower.Now())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(today) {
+		return fmt.Errorf("%s is in the past", last.Format(hosts.DateLayout))
+	}
+
+	return editFile(hostsFile, nower, func(f *hosts.File, _ *journal) error {
+		f.SetVacation(last)
+
+		return nil
+	})
+}
+
+// EndVacation removes the vacation from the hostsFile, returning whether there was one.
+func EndVacation(hostsFile string) (bool, error) {
+	var ended bool
+
+	err := editFile(hostsFile, DefaultNower{}, func(f *hosts.File, _ *journal) error {
+		ended = f.ClearVacation()
+
+		return nil
+	})
+
+	return ended, err
+}
@@ -0,0 +1,90 @@
+package cmds_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/cmd/freeblock/cmds"
+)
+
+func TestVacation(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, "0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4\n")
+
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	last := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	err := cmds.Vacation(hostsFile, last.AddDate(0, 0, -2), MockNower{now})
+	if err == nil {
+		t.Fatal("expected an error for a date in the past")
+	}
+
+	err = cmds.Vacation(hostsFile, last, MockNower{now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#freeblock:vacation 2026-10-18\n0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+
+	// The vacation doesn't last past the end of the day.
+	_, err = cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{now.AddDate(0, 0, 2)})
+	if err == nil {
+		t.Error("expected the schedule to apply after the vacation")
+	}
+
+	_, err = cmds.Unblock([]string{"reddit.com"}, hostsFile, MockNower{now.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ended, err := cmds.EndVacation(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ended {
+		t.Error("expected the vacation to end")
+	}
+	got, err = os.ReadFile(hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "1.2.3.4 reddit.com #freeblock:09-17\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Error("unexpected hosts file (-want +got):\n" + diff)
+	}
+}
+
+func TestUnblock_except(t *testing.T) {
+	t.Parallel()
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	writeString(t, hostsFile, `#freeblock:except 2026-12-24..2026-12-26
+0.0.0.0 reddit.com #freeblock:09-17 # 1.2.3.4
+`)
+
+	_, err := cmds.Unblock(
+		[]string{"reddit.com"}, hostsFile, MockNower{time.Date(2026, 12, 23, 10, 0, 0, 0, time.Local)},
+	)
+	if err == nil {
+		t.Error("expected the schedule to apply before the exception")
+	}
+
+	_, err = cmds.Unblock(
+		[]string{"reddit.com"}, hostsFile, MockNower{time.Date(2026, 12, 25, 10, 0, 0, 0, time.Local)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
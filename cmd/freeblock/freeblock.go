@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	_ "time/tzdata" // Time zones in schedules shouldn't depend on the system's zoneinfo.
 
 	"github.com/spf13/cobra"
 
@@ -18,14 +19,22 @@ var Cmd = &cobra.Command{
 func init() {
 	Cmd.AddCommand(
 		cmds.BlockCmd,
+		cmds.DaemonCmd,
+		cmds.DiffCmd,
+		cmds.EnforceCmd,
+		cmds.ExitCodesHelp,
 		cmds.OpenCmd,
+		cmds.RecoverCmd,
+		cmds.ServeBlockPageCmd,
+		cmds.StatusCmd,
 		cmds.UnblockCmd,
+		cmds.VacationCmd,
 	)
 }
 
 func main() {
 	if err := Cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(cmds.ExitError)
 	}
 }
@@ -0,0 +1,517 @@
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kind is the kind of a parsed line in a hosts file.
+type Kind int
+
+const (
+	// KindBlank is an empty or whitespace-only line.
+	KindBlank Kind = iota
+	// KindComment is a comment line, or any other line that isn't a host line.
+	KindComment
+	// KindHost is a (possibly commented-out) host line.
+	KindHost
+)
+
+// Entry is a single parsed line of a hosts file. Blank and comment lines are kept verbatim in Text,
+// while host lines are split into their parts. The whitespace of the original line is remembered
+// so that an unmodified Entry is written back exactly as it was read.
+type Entry struct {
+	Kind Kind
+
+	// Num is the line number (starting at 1) that the Entry was read from, or 0 if the Entry was
+	// added after the file was parsed.
+	Num int
+
+	// Text is the full text of a blank or comment line. It is unused for host lines.
+	Text string
+
+	// IP is the address of a host line.
+	IP string
+	// Hostnames holds the domain name and aliases of a host line.
+	Hostnames []string
+	// Disabled is true if the host line is commented out.
+	Disabled bool
+	// Comment is the inline comment at the end of a host line, starting with '#'. It includes any
+	// freeblock directives. Use SetComment to change it.
+	Comment string
+
+	indent     string   // whitespace before the line
+	gap        string   // whitespace between the '#' of a disabled line and the IP
+	seps       []string // whitespace before each hostname
+	commentSep string   // whitespace before the comment, or trailing whitespace if there is none
+	ending     string   // line ending, if it differs from the one in the Format of the File
+}
+
+// NewEntry returns a new host line Entry pointing the hostnames to ip.
+func NewEntry(ip string, hostnames ...string) *Entry {
+	return &Entry{
+		Kind:      KindHost,
+		IP:        ip,
+		Hostnames: hostnames,
+	}
+}
+
+// ParseLine parses a single line of a hosts file. The returned Entry has no line number.
+func ParseLine(l Line) *Entry {
+	s := string(l)
+
+	rest := strings.TrimLeftFunc(s, unicode.IsSpace)
+	if rest == "" {
+		return &Entry{Kind: KindBlank, Text: s}
+	}
+
+	e := &Entry{Kind: KindHost, indent: s[:len(s)-len(rest)]}
+
+	if rest[0] == '#' {
+		e.Disabled = true
+		rest = rest[1:]
+		e.gap, rest = splitSpace(rest)
+	}
+
+	e.IP, rest = splitToken(rest)
+	if !isIPAddress(e.IP) {
+		return &Entry{Kind: KindComment, Text: s}
+	}
+
+	for {
+		var sep string
+		sep, rest = splitSpace(rest)
+		if rest == "" || rest[0] == '#' {
+			e.commentSep = sep
+			e.Comment = rest
+
+			break
+		}
+
+		var hostname string
+		hostname, rest = splitToken(rest)
+		e.Hostnames = append(e.Hostnames, hostname)
+		e.seps = append(e.seps, sep)
+	}
+
+	if len(e.Hostnames) == 0 {
+		return &Entry{Kind: KindComment, Text: s}
+	}
+
+	return e
+}
+
+// splitSpace splits s after its leading whitespace.
+func splitSpace(s string) (space, rest string) {
+	rest = strings.TrimLeftFunc(s, unicode.IsSpace)
+
+	return s[:len(s)-len(rest)], rest
+}
+
+// splitToken splits s at the first whitespace or '#'.
+func splitToken(s string) (token, rest string) {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return r == '#' || unicode.IsSpace(r)
+	})
+	if end == -1 {
+		return s, ""
+	}
+
+	return s[:end], s[end:]
+}
+
+// Line renders the Entry as a line of a hosts file.
+func (e *Entry) Line() Line {
+	if e.Kind != KindHost {
+		return Line(e.Text)
+	}
+
+	var b strings.Builder
+
+	b.WriteString(e.indent)
+	if e.Disabled {
+		b.WriteByte('#')
+		b.WriteString(e.gap)
+	}
+	b.WriteString(e.IP)
+	for i, hostname := range e.Hostnames {
+		sep := " "
+		if i < len(e.seps) && e.seps[i] != "" {
+			sep = e.seps[i]
+		}
+		b.WriteString(sep)
+		b.WriteString(hostname)
+	}
+	b.WriteString(e.commentSep)
+	b.WriteString(e.Comment)
+
+	return Line(b.String())
+}
+
+// HasHostname returns whether hostname is one of the hostnames of the Entry.
+func (e *Entry) HasHostname(hostname string) bool {
+	for _, h := range e.Hostnames {
+		if h == hostname {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetComment replaces the inline comment of the Entry. The comment should start with '#'. Setting
+// an empty comment removes the comment along with the whitespace before it.
+func (e *Entry) SetComment(comment string) {
+	switch {
+	case comment == "":
+		e.commentSep = ""
+	case e.commentSep == "":
+		e.commentSep = " "
+	}
+	e.Comment = comment
+}
+
+// AppendComment adds text to the end of the inline comment, after a new '#'.
+func (e *Entry) AppendComment(text string) {
+	if e.Comment == "" {
+		e.SetComment("# " + text)
+
+		return
+	}
+	e.Comment += " # " + text
+}
+
+// DirectivePrefix starts a freeblock directive in the inline comment of a host line.
+const DirectivePrefix = "#freeblock:"
+
+// BlockedMarker marks a host line that freeblock blocked by pointing it to an address other than
+// 0.0.0.0 or ::, so that it can be told apart from loopback lines added by hand. It isn't one of
+// the Directives.
+const BlockedMarker = DirectivePrefix + "blocked"
+
+// Marked returns whether the inline comment of the Entry has the BlockedMarker.
+func (e *Entry) Marked() bool {
+	for _, f := range strings.Fields(e.Comment) {
+		if f == BlockedMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Mark adds the BlockedMarker to the inline comment of the Entry, unless it's there already.
+func (e *Entry) Mark() {
+	switch {
+	case e.Marked():
+	case e.Comment == "":
+		e.SetComment(BlockedMarker)
+	default:
+		e.Comment += " " + BlockedMarker
+	}
+}
+
+// Directives returns the values of the freeblock directives in the inline comment of the Entry. For
+// example, the value of "#freeblock:09-17" is "09-17".
+func (e *Entry) Directives() []string {
+	var out []string
+
+	for _, f := range strings.Fields(e.Comment) {
+		if !strings.HasPrefix(f, DirectivePrefix) || len(f) == len(DirectivePrefix) ||
+			f == BlockedMarker {
+			continue
+		}
+		out = append(out, f[len(DirectivePrefix):])
+	}
+
+	return out
+}
+
+// Schedule returns the Schedule made from all of the freeblock directives of the Entry. Directives
+// like "schedule=workhours" refer to a Schedule in named. If there are no directives, the Schedule
+// is empty. A *ParseError is returned if a directive is malformed or refers to an unknown Schedule.
+func (e *Entry) Schedule(named Schedules) (Schedule, error) {
+	var s Schedule
+
+	for _, d := range e.Directives() {
+		var (
+			ds  Schedule
+			err error
+		)
+		if strings.HasPrefix(d, scheduleRef) {
+			var ok bool
+			ds, ok = named[d[len(scheduleRef):]]
+			if !ok {
+				err = fmt.Errorf("unknown schedule %q", d[len(scheduleRef):])
+			}
+		} else {
+			ds, err = ParseSchedule(d)
+		}
+		if err != nil {
+			return Schedule{}, &ParseError{Line: e.Num, Directive: d, Err: err}
+		}
+
+		s.Windows = append(s.Windows, ds.Windows...)
+	}
+
+	return s, nil
+}
+
+// File is a parsed hosts file. Every line of the file is kept as an Entry, so that the file can be
+// written back without losing anything.
+type File struct {
+	Entries []*Entry
+	Format  Format
+}
+
+// Parse parses the lines of a hosts file.
+func Parse(lines []Line) *File {
+	f := &File{Entries: make([]*Entry, len(lines))}
+
+	for i, l := range lines {
+		e := ParseLine(l)
+		e.Num = i + 1
+		f.Entries[i] = e
+	}
+
+	return f
+}
+
+// Lines renders the File as a list of lines.
+func (f *File) Lines() []Line {
+	lines := make([]Line, len(f.Entries))
+
+	for i, e := range f.Entries {
+		lines[i] = e.Line()
+	}
+
+	return lines
+}
+
+// Hosts returns the host line entries of the File, in order.
+func (f *File) Hosts() []*Entry {
+	var out []*Entry
+
+	for _, e := range f.Entries {
+		if e.Kind == KindHost {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// Append adds an Entry to the end of the File.
+func (f *File) Append(e *Entry) {
+	f.Entries = append(f.Entries, e)
+}
+
+// Schedules returns the named schedules defined in the File. Each one is defined on a comment line
+// like this:
+//
+//	#freeblock:schedule workhours = mon-fri 09:00-17:30
+//
+// The definition uses the same syntax as a directive, except that the days, times and time zone may
+// be separated by spaces instead of '@'. A *ParseError is returned if a definition is malformed or
+// if a name is defined twice.
+func (f *File) Schedules() (Schedules, error) {
+	named := make(Schedules)
+
+	for _, e := range f.Entries {
+		def, ok := e.headerValue(scheduleKeyword)
+		if !ok {
+			continue
+		}
+
+		name, s, err := parseScheduleDef(def, named)
+		if err != nil {
+			return nil, e.headerError(err)
+		}
+		named[name] = s
+	}
+
+	return named, nil
+}
+
+// headerValue returns the rest of a comment line that starts with DirectivePrefix followed by the
+// keyword and whitespace.
+func (e *Entry) headerValue(keyword string) (value string, ok bool) {
+	if e.Kind != KindComment {
+		return "", false
+	}
+
+	text := strings.TrimSpace(e.Text)
+	value = strings.TrimPrefix(text, DirectivePrefix+keyword)
+	if len(value) == len(text) || !strings.HasPrefix(value, " ") && !strings.HasPrefix(value, "\t") {
+		return "", false
+	}
+
+	return strings.TrimSpace(value), true
+}
+
+// headerError returns a *ParseError for the comment line of the Entry.
+func (e *Entry) headerError(err error) *ParseError {
+	return &ParseError{
+		Line:      e.Num,
+		Directive: strings.TrimPrefix(strings.TrimSpace(e.Text), DirectivePrefix),
+		Err:       err,
+	}
+}
+
+// Exceptions returns the ranges of dates during which schedules don't apply. They're given on
+// comment lines like these:
+//
+//	#freeblock:except 2026-12-24..2026-12-26 2027-01-01
+//	#freeblock:vacation 2026-12-31
+//
+// An exception line lists single dates or ranges of dates, separated by spaces or commas. A
+// vacation line gives the last day of a vacation that has already started. A *ParseError is
+// returned if a line is malformed.
+func (f *File) Exceptions() ([]DateRange, error) {
+	var out []DateRange
+
+	for _, e := range f.Entries {
+		if value, ok := e.headerValue(exceptKeyword); ok {
+			for _, item := range strings.FieldsFunc(value, isListSeparator) {
+				r, err := ParseDateRange(item)
+				if err != nil {
+					return nil, e.headerError(err)
+				}
+				out = append(out, r)
+			}
+		}
+
+		if value, ok := e.headerValue(vacationKeyword); ok {
+			last, err := time.ParseInLocation(DateLayout, value, time.UTC)
+			if err != nil {
+				return nil, e.headerError(fmt.Errorf("invalid date %q", value))
+			}
+			out = append(out, DateRange{Last: last})
+		}
+	}
+
+	return out, nil
+}
+
+func isListSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+// SetVacation records a vacation lasting until the end of the date last. An existing vacation line
+// is replaced, and otherwise a new one is added at the top of the File.
+func (f *File) SetVacation(last time.Time) {
+	text := DirectivePrefix + vacationKeyword + " " + last.Format(DateLayout)
+	e := &Entry{Kind: KindComment, Text: text}
+
+	for i, old := range f.Entries {
+		if _, ok := old.headerValue(vacationKeyword); ok {
+			// Remove any others, and then put the new line in place of this one.
+			e.Num = old.Num
+			f.ClearVacation()
+			f.Entries = append(f.Entries[:i], append([]*Entry{e}, f.Entries[i:]...)...)
+
+			return
+		}
+	}
+
+	f.Entries = append([]*Entry{e}, f.Entries...)
+}
+
+// ClearVacation removes any vacation lines from the File, returning whether there were any.
+func (f *File) ClearVacation() bool {
+	kept := f.Entries[:0]
+
+	for _, e := range f.Entries {
+		if _, ok := e.headerValue(vacationKeyword); !ok {
+			kept = append(kept, e)
+		}
+	}
+
+	removed := len(kept) != len(f.Entries)
+	f.Entries = kept
+
+	return removed
+}
+
+// Sink returns the addresses that blocked domains should resolve to, given on a comment line like
+// this:
+//
+//	#freeblock:sink 127.0.0.1 ::1
+//
+// Either address may be left out, in which case it is returned as the empty string. A *ParseError
+// is returned if the line is malformed or if there is more than one.
+func (f *File) Sink() (v4, v6 string, err error) {
+	var found *Entry
+
+	for _, e := range f.Entries {
+		value, ok := e.headerValue(sinkKeyword)
+		if !ok {
+			continue
+		}
+		if found != nil {
+			return "", "", e.headerError(fmt.Errorf("sink is already set on line %d", found.Num))
+		}
+		found = e
+
+		v4, v6, err = ParseSink(value)
+		if err != nil {
+			return "", "", e.headerError(err)
+		}
+	}
+
+	return v4, v6, nil
+}
+
+// ParseSink parses one IPv4 address and one IPv6 address, either of which may be left out,
+// separated by spaces or commas.
+func ParseSink(s string) (v4, v6 string, err error) {
+	addrs := strings.FieldsFunc(s, isListSeparator)
+	if len(addrs) == 0 {
+		return "", "", errors.New("missing address")
+	}
+
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			return "", "", fmt.Errorf("invalid address %q", addr)
+		case ip.To4() != nil && !strings.Contains(addr, ":"):
+			if v4 != "" {
+				return "", "", errors.New("more than one IPv4 address")
+			}
+			v4 = addr
+		default:
+			if v6 != "" {
+				return "", "", errors.New("more than one IPv6 address")
+			}
+			v6 = addr
+		}
+	}
+
+	return v4, v6, nil
+}
+
+func parseScheduleDef(def string, named Schedules) (name string, s Schedule, err error) {
+	parts := strings.SplitN(def, "=", 2)
+	if len(parts) != 2 {
+		return "", Schedule{}, errors.New("expected 'name = schedule'")
+	}
+
+	name = strings.TrimSpace(parts[0])
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", Schedule{}, fmt.Errorf("invalid schedule name %q", name)
+	}
+	if _, ok := named[name]; ok {
+		return "", Schedule{}, fmt.Errorf("schedule %q is defined more than once", name)
+	}
+
+	value := strings.Join(strings.Fields(parts[1]), "@")
+	value = strings.NewReplacer(",@", ",", "@,", ",").Replace(value)
+	s, err = ParseSchedule(value)
+
+	return name, s, err
+}
@@ -0,0 +1,271 @@
+package hosts_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+func TestParse_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "messy_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := hosts.ReadLines(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := hosts.Parse(lines)
+
+	var out bytes.Buffer
+
+	err = hosts.WriteLines(&out, f.Lines())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := cmp.Diff(string(b), out.String())
+	if diff != "" {
+		t.Error("unexpected output bytes (-want +got):\n" + diff)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   hosts.Line
+		want hosts.Entry
+	}{
+		"empty":  {"", hosts.Entry{Kind: hosts.KindBlank}},
+		"spaces": {" \t ", hosts.Entry{Kind: hosts.KindBlank, Text: " \t "}},
+		"comment": {" # this is a comment", hosts.Entry{
+			Kind: hosts.KindComment, Text: " # this is a comment",
+		}},
+		"normal": {" 0.0.0.0   google.com", hosts.Entry{
+			Kind: hosts.KindHost, IP: "0.0.0.0", Hostnames: []string{"google.com"},
+		}},
+		"aliases": {"1.1.1.1 google.com google", hosts.Entry{
+			Kind: hosts.KindHost, IP: "1.1.1.1", Hostnames: []string{"google.com", "google"},
+		}},
+		"commented": {"# 2.2.2.2 twitter.com", hosts.Entry{
+			Kind: hosts.KindHost, IP: "2.2.2.2", Hostnames: []string{"twitter.com"}, Disabled: true,
+		}},
+		"timed": {"1.1.1.1 google.com  #freeblock:08-17 # 1.2.3.4", hosts.Entry{
+			Kind: hosts.KindHost, IP: "1.1.1.1", Hostnames: []string{"google.com"},
+			Comment: "#freeblock:08-17 # 1.2.3.4",
+		}},
+		"no_space": {"::1 localhost#freeblock:08-17", hosts.Entry{
+			Kind: hosts.KindHost, IP: "::1", Hostnames: []string{"localhost"},
+			Comment: "#freeblock:08-17",
+		}},
+		"no_hostnames": {"#1.1.1.1 # nothing", hosts.Entry{
+			Kind: hosts.KindComment, Text: "#1.1.1.1 # nothing",
+		}},
+		"bad_ip": {"localhost 1.1.1.1", hosts.Entry{Kind: hosts.KindComment, Text: "localhost 1.1.1.1"}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := hosts.ParseLine(tc.in)
+
+			diff := cmp.Diff(tc.want, *got, cmpopts.IgnoreUnexported(hosts.Entry{}))
+			if diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+			diff = cmp.Diff(tc.in, got.Line())
+			if diff != "" {
+				t.Error("unexpected rendered line (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestEntry_modify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in     hosts.Line
+		modify func(e *hosts.Entry)
+		want   hosts.Line
+	}{
+		"set_ip": {
+			" 1.1.1.1\tgoogle.com  google # hi",
+			func(e *hosts.Entry) { e.IP = "0.0.0.0" },
+			" 0.0.0.0\tgoogle.com  google # hi",
+		},
+		"disable": {
+			"  1.1.1.1 google.com",
+			func(e *hosts.Entry) { e.Disabled = true },
+			"  #1.1.1.1 google.com",
+		},
+		"enable": {
+			"#  1.1.1.1 google.com",
+			func(e *hosts.Entry) { e.Disabled = false },
+			"1.1.1.1 google.com",
+		},
+		"add_hostname": {
+			"1.1.1.1\tgoogle.com # hi",
+			func(e *hosts.Entry) { e.Hostnames = append(e.Hostnames, "google") },
+			"1.1.1.1\tgoogle.com google # hi",
+		},
+		"append_comment": {
+			"1.1.1.1 google.com",
+			func(e *hosts.Entry) { e.AppendComment("2.2.2.2") },
+			"1.1.1.1 google.com # 2.2.2.2",
+		},
+		"append_comment2": {
+			"1.1.1.1 google.com  #freeblock:08-17",
+			func(e *hosts.Entry) { e.AppendComment("2.2.2.2") },
+			"1.1.1.1 google.com  #freeblock:08-17 # 2.2.2.2",
+		},
+		"mark": {
+			"127.0.0.1 google.com",
+			func(e *hosts.Entry) { e.Mark() },
+			"127.0.0.1 google.com #freeblock:blocked",
+		},
+		"mark2": {
+			"127.0.0.1 google.com  # hi",
+			func(e *hosts.Entry) { e.Mark() },
+			"127.0.0.1 google.com  # hi #freeblock:blocked",
+		},
+		"mark_again": {
+			"127.0.0.1 google.com #freeblock:blocked",
+			func(e *hosts.Entry) { e.Mark() },
+			"127.0.0.1 google.com #freeblock:blocked",
+		},
+		"remove_comment": {
+			"1.1.1.1 google.com  # 2.2.2.2",
+			func(e *hosts.Entry) { e.SetComment("") },
+			"1.1.1.1 google.com",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			e := hosts.ParseLine(tc.in)
+			tc.modify(e)
+
+			diff := cmp.Diff(tc.want, e.Line())
+			if diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestEntry_Directives(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   hosts.Line
+		want []string
+	}{
+		"none":     {"1.1.1.1 google.com # hi", nil},
+		"one":      {"1.1.1.1 google.com #freeblock:08-17 # hi", []string{"08-17"}},
+		"two":      {"1.1.1.1 google.com #freeblock:08-12 #freeblock:13-17", []string{"08-12", "13-17"}},
+		"empty":    {"1.1.1.1 google.com #freeblock: 08-17", nil},
+		"no_space": {"1.1.1.1 google.com#freeblock:08-17", []string{"08-17"}},
+		"marker":   {"1.1.1.1 google.com #freeblock:blocked #freeblock:08-17", []string{"08-17"}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := hosts.ParseLine(tc.in).Directives()
+
+			diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty())
+			if diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	f := hosts.Parse([]hosts.Line{
+		"# Host addresses",
+		"127.0.0.1  localhost",
+		"",
+		"#0.0.0.0 google.com",
+	})
+
+	var nums []int
+	for _, e := range f.Hosts() {
+		nums = append(nums, e.Num)
+	}
+	if diff := cmp.Diff([]int{2, 4}, nums); diff != "" {
+		t.Error("unexpected host line numbers (-want +got):\n" + diff)
+	}
+
+	f.Append(hosts.NewEntry("0.0.0.0", "example.com", "www.example.com"))
+
+	want := []hosts.Line{
+		"# Host addresses",
+		"127.0.0.1  localhost",
+		"",
+		"#0.0.0.0 google.com",
+		"0.0.0.0 example.com www.example.com",
+	}
+	if diff := cmp.Diff(want, f.Lines()); diff != "" {
+		t.Error("unexpected output lines (-want +got):\n" + diff)
+	}
+}
+
+func TestFile_Sink(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		lines  []hosts.Line
+		v4, v6 string
+		errOn  int
+	}{
+		"none":     {[]hosts.Line{"0.0.0.0 reddit.com"}, "", "", 0},
+		"v4":       {[]hosts.Line{"#freeblock:sink 127.0.0.1"}, "127.0.0.1", "", 0},
+		"both":     {[]hosts.Line{"#freeblock:sink ::1, 127.0.0.1"}, "127.0.0.1", "::1", 0},
+		"invalid":  {[]hosts.Line{"", "#freeblock:sink localhost"}, "", "", 2},
+		"two_v4":   {[]hosts.Line{"#freeblock:sink 127.0.0.1 127.0.0.2"}, "", "", 1},
+		"twice":    {[]hosts.Line{"#freeblock:sink ::1", "#freeblock:sink ::1"}, "", "", 2},
+		"no_value": {[]hosts.Line{"#freeblock:sink "}, "", "", 0},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			v4, v6, err := hosts.Parse(tc.lines).Sink()
+
+			var parseErr *hosts.ParseError
+			switch {
+			case tc.errOn != 0 && (!errors.As(err, &parseErr) || parseErr.Line != tc.errOn):
+				t.Errorf("expected a parse error on line %d, got %v", tc.errOn, err)
+			case tc.errOn == 0 && err != nil:
+				t.Error(err)
+			case v4 != tc.v4 || v6 != tc.v6:
+				t.Errorf("expected %q and %q, got %q and %q", tc.v4, tc.v6, v4, v6)
+			}
+		})
+	}
+}
@@ -130,6 +130,8 @@ func (l *Line) Uncomment() {
 
 // Timing returns the times when the line shouldn't be unblocked. If that isn't specified or if this
 // isn't a host line at all, zeros are returned.
+//
+// Deprecated: Timing only understands whole hours. Use Entry.Schedule instead.
 func (l *Line) Timing() (start, end int) {
 	s := strings.TrimSpace(string(*l))
 
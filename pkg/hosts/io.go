@@ -2,32 +2,171 @@ package hosts
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// Format describes the parts of a hosts file's encoding that aren't kept in its lines. The zero
+// value is a file with "\n" line endings, no byte order mark, and a newline at the end.
+type Format struct {
+	// CRLF is set if lines end with "\r\n" instead of "\n".
+	CRLF bool
+	// BOM is set if the file starts with a UTF-8 byte order mark.
+	BOM bool
+	// NoFinalNewline is set if the last line doesn't end with a line ending.
+	NoFinalNewline bool
+}
+
+const bom = "\ufeff"
+
 // ReadLines returns the lines in the specified file.
 func ReadLines(r io.Reader) ([]Line, error) {
-	var lines []Line
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		lines = append(lines, Line(scanner.Text()))
+	lines, _, err := ReadFormat(r)
+
+	return lines, err
+}
+
+// ReadFormat returns the lines in the specified file along with the Format of the file. The line
+// ending style is taken from the first line. In a file with "\n" line endings, any "\r" before a
+// line ending is left in the line so that it can be written back. Use Read to keep the line endings
+// of every line without leaving them in the lines.
+func ReadFormat(r io.Reader) ([]Line, Format, error) {
+	lines, endings, format, err := readFormat(r)
+	if !format.CRLF {
+		for i, ending := range endings {
+			if ending == crlf {
+				lines[i] += "\r"
+			}
+		}
 	}
-	if err := scanner.Err(); err != nil {
-		return lines, fmt.Errorf("read file: %w", err)
+
+	return lines, format, err
+}
+
+const crlf = "\r\n"
+
+// readFormat returns the lines in the specified file without their line endings, along with the
+// ending of each line and the Format of the file. The ending of a last line without one is empty.
+func readFormat(r io.Reader) (lines []Line, endings []string, format Format, err error) {
+	br := bufio.NewReader(r)
+	for {
+		s, readErr := br.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return lines, endings, format, fmt.Errorf("read file: %w", readErr)
+		}
+		if s == "" {
+			// Either the file is empty or the last line ended with a newline.
+			break
+		}
+
+		if len(lines) == 0 && strings.HasPrefix(s, bom) {
+			format.BOM = true
+			s = s[len(bom):]
+		}
+
+		if !strings.HasSuffix(s, "\n") {
+			format.NoFinalNewline = true
+			lines = append(lines, Line(s))
+			endings = append(endings, "")
+
+			break
+		}
+
+		ending := "\n"
+		if strings.HasSuffix(s, crlf) {
+			ending = crlf
+		}
+		if len(lines) == 0 {
+			format.CRLF = ending == crlf
+		}
+
+		lines = append(lines, Line(s[:len(s)-len(ending)]))
+		endings = append(endings, ending)
 	}
 
-	return lines, nil
+	return lines, endings, format, nil
 }
 
 // WriteLines writes the lines to the specified path.
 func WriteLines(w io.Writer, lines []Line) error {
-	for _, line := range lines {
-		_, err := w.Write([]byte(string(line) + "\n"))
+	return WriteFormat(w, lines, Format{})
+}
+
+// WriteFormat writes the lines to w using the line endings and other details from format.
+func WriteFormat(w io.Writer, lines []Line, format Format) error {
+	return writeFormat(w, lines, nil, format)
+}
+
+// writeFormat is like WriteFormat, but a line with a non-empty ending in endings is ended with it
+// instead of the line ending from format.
+func writeFormat(w io.Writer, lines []Line, endings []string, format Format) error {
+	bw := bufio.NewWriter(w)
+
+	if format.BOM {
+		_, err := bw.WriteString(bom)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	for i, line := range lines {
+		_, err := bw.WriteString(string(line))
+		if err != nil {
+			return err
+		}
+
+		if i == len(lines)-1 && format.NoFinalNewline {
+			break
+		}
+		ending := format.lineEnding()
+		if i < len(endings) && endings[i] != "" {
+			ending = endings[i]
+		}
+		_, err = bw.WriteString(ending)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// lineEnding returns the line ending of the Format.
+func (format Format) lineEnding() string {
+	if format.CRLF {
+		return crlf
+	}
+
+	return "\n"
+}
+
+// Read parses a hosts file from r. Lines that end differently from the first line keep their own
+// line endings when the File is written.
+func Read(r io.Reader) (*File, error) {
+	lines, endings, format, err := readFormat(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := Parse(lines)
+	f.Format = format
+	for i, e := range f.Entries {
+		if endings[i] != format.lineEnding() {
+			e.ending = endings[i]
+		}
+	}
+
+	return f, nil
+}
+
+// Write writes the File to w in its original Format.
+func (f *File) Write(w io.Writer) error {
+	endings := make([]string, len(f.Entries))
+	for i, e := range f.Entries {
+		endings[i] = e.ending
+	}
+
+	return writeFormat(w, f.Lines(), endings, f.Format)
 }
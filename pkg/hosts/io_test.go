@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -52,3 +53,141 @@ func TestReadWriteLines(t *testing.T) {
 		t.Error("unexpected output bytes (-want +got):\n" + diff)
 	}
 }
+
+func TestReadWriteFormat(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 100*1024)
+
+	tests := map[string]struct {
+		in         string
+		wantLines  []hosts.Line
+		wantFormat hosts.Format
+	}{
+		"empty": {"", nil, hosts.Format{}},
+		"lf":    {"a\n\nb\n", []hosts.Line{"a", "", "b"}, hosts.Format{}},
+		"crlf":  {"a\r\n\r\nb\r\n", []hosts.Line{"a", "", "b"}, hosts.Format{CRLF: true}},
+		"no_final_newline": {
+			"a\nb", []hosts.Line{"a", "b"}, hosts.Format{NoFinalNewline: true},
+		},
+		"crlf_no_final_newline": {
+			"a\r\nb", []hosts.Line{"a", "b"}, hosts.Format{CRLF: true, NoFinalNewline: true},
+		},
+		"bom": {"\ufeffa\r\nb\r\n", []hosts.Line{"a", "b"}, hosts.Format{CRLF: true, BOM: true}},
+		"mixed": {
+			// The first line decides the line endings, and the stray "\r" stays in its line.
+			"a\nb\r\nc\n", []hosts.Line{"a", "b\r", "c"}, hosts.Format{},
+		},
+		"long": {long + "\nb\n", []hosts.Line{hosts.Line(long), "b"}, hosts.Format{}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lines, format, err := hosts.ReadFormat(strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diff := cmp.Diff(tc.wantLines, lines)
+			if diff != "" {
+				t.Error("unexpected output lines (-want +got):\n" + diff)
+			}
+			diff = cmp.Diff(tc.wantFormat, format)
+			if diff != "" {
+				t.Error("unexpected format (-want +got):\n" + diff)
+			}
+
+			var out bytes.Buffer
+
+			err = hosts.WriteFormat(&out, lines, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diff = cmp.Diff(tc.in, out.String())
+			if diff != "" {
+				t.Error("unexpected output bytes (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestFile_appendWithoutFinalNewline(t *testing.T) {
+	t.Parallel()
+
+	f, err := hosts.Read(strings.NewReader("127.0.0.1 localhost\r\n# end"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Append(hosts.NewEntry("0.0.0.0", "example.com"))
+
+	var out bytes.Buffer
+
+	err = f.Write(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "127.0.0.1 localhost\r\n# end\r\n0.0.0.0 example.com"
+	diff := cmp.Diff(want, out.String())
+	if diff != "" {
+		t.Error("unexpected output bytes (-want +got):\n" + diff)
+	}
+}
+
+func TestFile_mixedLineEndings(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"lf_first": {
+			"127.0.0.1 localhost\n1.2.3.4 reddit.com\r\n# end\n",
+			"127.0.0.1 localhost\n0.0.0.0 reddit.com # 1.2.3.4\r\n# end\n0.0.0.0 example.com\n",
+		},
+		"crlf_first": {
+			"127.0.0.1 localhost\r\n1.2.3.4 reddit.com\n# end\r\n",
+			"127.0.0.1 localhost\r\n0.0.0.0 reddit.com # 1.2.3.4\n# end\r\n" +
+				"0.0.0.0 example.com\r\n",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := hosts.Read(strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err = f.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.in, out.String()); diff != "" {
+				t.Error("unexpected output bytes before editing (-want +got):\n" + diff)
+			}
+
+			// Each line keeps its own ending, and new lines get the ending of the first line.
+			e := f.Entries[1]
+			e.AppendComment(e.IP)
+			e.IP = "0.0.0.0"
+			f.Append(hosts.NewEntry("0.0.0.0", "example.com"))
+
+			out.Reset()
+			if err = f.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Error("unexpected output bytes (-want +got):\n" + diff)
+			}
+		})
+	}
+}
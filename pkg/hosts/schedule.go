@@ -0,0 +1,441 @@
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeOfDay is a time of day, in minutes after midnight. 24:00 is allowed as the end of a Window.
+type TimeOfDay int
+
+const endOfDay TimeOfDay = 24 * 60
+
+// ParseTimeOfDay parses a time of day like "09" or "08:30".
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	hh, mm := s, "00"
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		hh, mm = s[:i], s[i+1:]
+		if len(mm) != 2 {
+			return 0, fmt.Errorf("invalid time of day %q", s)
+		}
+	}
+
+	h, okH := parseDigits(hh)
+	m, okM := parseDigits(mm)
+	t := TimeOfDay(h*60 + m)
+	if !okH || !okM || m >= 60 || t > endOfDay {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+
+	return t, nil
+}
+
+// parseDigits parses a decimal number of one or two digits.
+func parseDigits(s string) (int, bool) {
+	if len(s) == 0 || len(s) > 2 {
+		return 0, false
+	}
+
+	var n int
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	return n, true
+}
+
+// timeOfDay returns the time of day of t in its location.
+func timeOfDay(t time.Time) TimeOfDay {
+	return TimeOfDay(t.Hour()*60 + t.Minute())
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", t/60, t%60)
+}
+
+// Weekdays is a set of days of the week.
+type Weekdays uint8
+
+// EveryDay contains all days of the week.
+const EveryDay Weekdays = 1<<7 - 1
+
+var weekdayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseWeekdays parses a set of days like "mon-fri" or "sat+sun". A range may wrap around the end
+// of the week, as in "fri-mon".
+func ParseWeekdays(s string) (Weekdays, error) {
+	var days Weekdays
+
+	for _, part := range strings.Split(s, "+") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return 0, fmt.Errorf("invalid days %q", s)
+		}
+
+		first, ok := parseWeekday(bounds[0])
+		if !ok {
+			return 0, fmt.Errorf("invalid day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, ok = parseWeekday(bounds[1])
+			if !ok {
+				return 0, fmt.Errorf("invalid day %q", bounds[1])
+			}
+		}
+
+		for d := first; ; d = (d + 1) % 7 {
+			days |= 1 << d
+			if d == last {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for i, name := range weekdayNames {
+		if strings.EqualFold(s, name) {
+			return time.Weekday(i), true
+		}
+	}
+
+	return 0, false
+}
+
+// Has returns whether day is in the set.
+func (d Weekdays) Has(day time.Weekday) bool {
+	return d&(1<<day) != 0
+}
+
+// String returns the set in the form read by ParseWeekdays, with the week starting on Monday.
+func (d Weekdays) String() string {
+	var parts []string
+
+	for i := 0; i < 7; i++ {
+		day := time.Weekday((i + 1) % 7)
+		if !d.Has(day) {
+			continue
+		}
+
+		// Extend the run as far as it goes.
+		last := day
+		for i+1 < 7 && d.Has(time.Weekday((i+2)%7)) {
+			i++
+			last = time.Weekday((i + 1) % 7)
+		}
+
+		if last == day {
+			parts = append(parts, weekdayNames[day])
+		} else {
+			parts = append(parts, weekdayNames[day]+"-"+weekdayNames[last])
+		}
+	}
+
+	return strings.Join(parts, "+")
+}
+
+// Window is a daily range of time during which a host line shouldn't be unblocked. The range
+// includes Start but not End. If End is before Start, the range crosses midnight, so "22-06" covers
+// the night from 22:00 until 06:00 the next morning.
+type Window struct {
+	// Days limits the Window to certain days of the week. For a Window that crosses midnight, the
+	// day is the one on which the Window starts. If Days is zero, the Window applies every day.
+	Days       Weekdays
+	Start, End TimeOfDay
+	// Location is the time zone that the Window is evaluated in. If it's nil, the location of the
+	// time passed to Contains is used.
+	Location *time.Location
+}
+
+// ParseWindow parses a time range like "09-17" or "08:30-12:15". The range may be limited to some
+// days of the week with a prefix like "mon-fri@".
+func ParseWindow(s string) (Window, error) {
+	var w Window
+
+	times := s
+	if i := strings.IndexByte(s, '@'); i != -1 {
+		days, err := ParseWeekdays(s[:i])
+		if err != nil {
+			return Window{}, err
+		}
+		w.Days = days
+		times = s[i+1:]
+	}
+
+	parts := strings.Split(times, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("invalid time range %q", times)
+	}
+
+	var err error
+	w.Start, err = ParseTimeOfDay(parts[0])
+	if err != nil {
+		return Window{}, err
+	}
+	w.End, err = ParseTimeOfDay(parts[1])
+	if err != nil {
+		return Window{}, err
+	}
+
+	return w, nil
+}
+
+// Contains returns whether t falls inside the Window. Times of day are compared on the wall clock
+// of the Window's Location, so a Window keeps the same local hours across daylight saving changes.
+func (w Window) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	tod := timeOfDay(t)
+
+	if w.End < w.Start {
+		if tod >= w.Start {
+			return w.onDay(t.Weekday())
+		}
+
+		// The early morning part belongs to the Window that started the day before.
+		return tod < w.End && w.onDay((t.Weekday()+6)%7)
+	}
+
+	return tod >= w.Start && tod < w.End && w.onDay(t.Weekday())
+}
+
+// NextChange returns the first time after t at which the Window starts or ends. ok is false if the
+// Window never starts.
+func (w Window) NextChange(t time.Time) (next time.Time, ok bool) {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	if w.Start == w.End {
+		return time.Time{}, false
+	}
+
+	// Start the day before, in case a Window that crosses midnight started then.
+	for i := -1; i <= 7; i++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, t.Location())
+		if !w.onDay(day.Weekday()) {
+			continue
+		}
+
+		start, end := atTime(day, w.Start), atTime(day, w.End)
+		if w.End < w.Start {
+			end = atTime(day.AddDate(0, 0, 1), w.End)
+		}
+		for _, c := range [...]time.Time{start, end} {
+			if c.After(t) && (next.IsZero() || c.Before(next)) {
+				next = c
+			}
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// atTime returns the time on day at the time of day.
+func atTime(day time.Time, tod TimeOfDay) time.Time {
+	h, m := int(tod/60), int(tod%60)
+
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location())
+}
+
+func (w Window) onDay(day time.Weekday) bool {
+	return w.Days == 0 || w.Days.Has(day)
+}
+
+func (w Window) String() string {
+	s := w.Start.String() + "-" + w.End.String()
+	if w.Days != 0 && w.Days != EveryDay {
+		s = w.Days.String() + "@" + s
+	}
+	if w.Location != nil {
+		s += "@" + w.Location.String()
+	}
+
+	return s
+}
+
+// Schedule is the parsed form of the freeblock directives of a host line. It describes when the
+// line shouldn't be unblocked.
+type Schedule struct {
+	Windows []Window
+}
+
+// Schedules holds named schedules, which host lines can refer to with a directive like
+// "#freeblock:schedule=workhours".
+type Schedules map[string]Schedule
+
+const (
+	scheduleRef = "schedule="
+
+	// Keywords of the directives on comment lines.
+	scheduleKeyword = "schedule"
+	exceptKeyword   = "except"
+	vacationKeyword = "vacation"
+	sinkKeyword     = "sink"
+)
+
+// ParseSchedule parses the value of a freeblock directive, which is a comma-separated list of
+// windows like "09-12,13-17". Days given for one window also apply to the windows after it that
+// don't have their own, so "mon-fri@09-12,13-17" only applies on weekdays. The directive may end
+// with a time zone, as in "09-17@America/Denver", which applies to all of its windows.
+func ParseSchedule(directive string) (Schedule, error) {
+	var (
+		s    Schedule
+		days Weekdays
+		loc  *time.Location
+	)
+
+	if i := strings.LastIndexByte(directive, '@'); i != -1 && !startsWithDigit(directive[i+1:]) {
+		name := directive[i+1:]
+		directive = directive[:i]
+
+		var err error
+		loc, err = loadLocation(name)
+		if err != nil {
+			return Schedule{}, err
+		}
+	}
+
+	for _, part := range strings.Split(directive, ",") {
+		w, err := ParseWindow(part)
+		if err != nil {
+			return Schedule{}, err
+		}
+
+		if w.Days == 0 {
+			w.Days = days
+		}
+		days = w.Days
+		w.Location = loc
+
+		s.Windows = append(s.Windows, w)
+	}
+
+	return s, nil
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// loadLocation is like time.LoadLocation, but it doesn't accept an empty name.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, errors.New("empty time zone")
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	return loc, nil
+}
+
+// Active returns the first Window of the Schedule that contains t.
+func (s Schedule) Active(t time.Time) (w Window, ok bool) {
+	for _, w = range s.Windows {
+		if w.Contains(t) {
+			return w, true
+		}
+	}
+
+	return Window{}, false
+}
+
+// NextChange returns the first time after t at which one of the Windows of the Schedule starts or
+// ends. ok is false if none of them ever start.
+func (s Schedule) NextChange(t time.Time) (next time.Time, ok bool) {
+	for _, w := range s.Windows {
+		if c, ok := w.NextChange(t); ok && (next.IsZero() || c.Before(next)) {
+			next = c
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// DateLayout is the layout of the dates in exception and vacation lines.
+const DateLayout = "2006-01-02"
+
+// DateRange is a range of calendar days during which schedules don't apply. First and Last are
+// midnight UTC on the first and last days of the range. If First is zero, the range has no start.
+type DateRange struct {
+	First, Last time.Time
+}
+
+// ParseDateRange parses a date like "2026-12-25" or a range of dates like "2026-12-24..2026-12-26".
+func ParseDateRange(s string) (DateRange, error) {
+	parts := strings.Split(s, "..")
+	if len(parts) > 2 {
+		return DateRange{}, fmt.Errorf("invalid date range %q", s)
+	}
+
+	var (
+		r   DateRange
+		err error
+	)
+	r.First, err = time.ParseInLocation(DateLayout, parts[0], time.UTC)
+	if err != nil {
+		return DateRange{}, fmt.Errorf("invalid date %q", parts[0])
+	}
+	r.Last = r.First
+	if len(parts) == 2 {
+		r.Last, err = time.ParseInLocation(DateLayout, parts[1], time.UTC)
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q", parts[1])
+		}
+	}
+
+	if r.Last.Before(r.First) {
+		return DateRange{}, fmt.Errorf("date range %q ends before it starts", s)
+	}
+
+	return r, nil
+}
+
+// Contains returns whether the calendar day of t, in its own location, is in the range.
+func (r DateRange) Contains(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	return !day.Before(r.First) && !day.After(r.Last)
+}
+
+func (r DateRange) String() string {
+	switch {
+	case r.First.IsZero():
+		return ".." + r.Last.Format(DateLayout)
+	case r.First.Equal(r.Last):
+		return r.First.Format(DateLayout)
+	default:
+		return r.First.Format(DateLayout) + ".." + r.Last.Format(DateLayout)
+	}
+}
+
+// ParseError is returned when a freeblock directive can't be parsed.
+type ParseError struct {
+	// Line is the line number of the directive, or 0 if it's not known.
+	Line      int
+	Directive string
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("invalid directive %q: %v", e.Directive, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: invalid directive %q: %v", e.Line, e.Directive, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
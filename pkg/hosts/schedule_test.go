@@ -0,0 +1,603 @@
+package hosts_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/pkg/hosts"
+)
+
+func TestParseWindow(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    hosts.Window
+		wantErr bool
+	}{
+		"hours":        {in: "09-17", want: hosts.Window{Start: 9 * 60, End: 17 * 60}},
+		"single_digit": {in: "9-17", want: hosts.Window{Start: 9 * 60, End: 17 * 60}},
+		"minutes":      {in: "08:30-12:15", want: hosts.Window{Start: 8*60 + 30, End: 12*60 + 15}},
+		"mixed":        {in: "08:30-17", want: hosts.Window{Start: 8*60 + 30, End: 17 * 60}},
+		"end_of_day":   {in: "22-24:00", want: hosts.Window{Start: 22 * 60, End: 24 * 60}},
+		"bad_minutes":  {in: "08:3-12", wantErr: true},
+		"60_minutes":   {in: "08:60-12", wantErr: true},
+		"25_hours":     {in: "08-25", wantErr: true},
+		"after_24":     {in: "08-24:01", wantErr: true},
+		"sign":         {in: "+8-12", wantErr: true},
+		"no_end":       {in: "08", wantErr: true},
+		"three":        {in: "08-12-17", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hosts.ParseWindow(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		w     hosts.Window
+		clock string
+		want  bool
+	}{
+		{hosts.Window{Start: 8*60 + 30, End: 12*60 + 15}, "08:29", false},
+		{hosts.Window{Start: 8*60 + 30, End: 12*60 + 15}, "08:30", true},
+		{hosts.Window{Start: 8*60 + 30, End: 12*60 + 15}, "12:14", true},
+		{hosts.Window{Start: 8*60 + 30, End: 12*60 + 15}, "12:15", false},
+		// overnight
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "21:59", false},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "22:00", true},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "23:59", true},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "00:00", true},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "05:59", true},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "06:00", false},
+		{hosts.Window{Start: 22 * 60, End: 6 * 60}, "12:00", false},
+		// empty
+		{hosts.Window{Start: 6 * 60, End: 6 * 60}, "06:00", false},
+	}
+
+	for _, tc := range tests {
+		now, err := time.Parse("15:04", tc.clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tc.w.Contains(now); got != tc.want {
+			t.Errorf("%v.Contains(%s) = %v, want %v", tc.w, tc.clock, got, tc.want)
+		}
+	}
+}
+
+func TestWindow_days(t *testing.T) {
+	t.Parallel()
+
+	workHours, err := hosts.ParseWindow("mon-fri@09-17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fridayNight, err := hosts.ParseWindow("fri@22-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// October 4, 2021 was a Monday.
+	tests := []struct {
+		w    hosts.Window
+		t    time.Time
+		want bool
+	}{
+		{workHours, time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC), true},
+		{workHours, time.Date(2021, 10, 8, 16, 59, 0, 0, time.UTC), true},
+		{workHours, time.Date(2021, 10, 8, 17, 0, 0, 0, time.UTC), false},
+		{workHours, time.Date(2021, 10, 9, 12, 0, 0, 0, time.UTC), false},
+		{workHours, time.Date(2021, 10, 10, 12, 0, 0, 0, time.UTC), false},
+		{fridayNight, time.Date(2021, 10, 7, 23, 0, 0, 0, time.UTC), false},
+		{fridayNight, time.Date(2021, 10, 8, 5, 0, 0, 0, time.UTC), false},
+		{fridayNight, time.Date(2021, 10, 8, 23, 0, 0, 0, time.UTC), true},
+		{fridayNight, time.Date(2021, 10, 9, 5, 0, 0, 0, time.UTC), true},
+		{fridayNight, time.Date(2021, 10, 9, 23, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tc := range tests {
+		if got := tc.w.Contains(tc.t); got != tc.want {
+			t.Errorf("%v.Contains(%v) = %v, want %v", tc.w, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestParseWeekdays(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		"one":      {in: "tue", want: "tue"},
+		"range":    {in: "mon-fri", want: "mon-fri"},
+		"plus":     {in: "sat+sun", want: "sat-sun"},
+		"mixed":    {in: "mon-wed+fri", want: "mon-wed+fri"},
+		"wrapping": {in: "fri-mon", want: "mon+fri-sun"},
+		"case":     {in: "Mon-FRI", want: "mon-fri"},
+		"all":      {in: "mon-sun", want: "mon-sun"},
+		"unknown":  {in: "monday", wantErr: true},
+		"empty":    {in: "", wantErr: true},
+		"too_many": {in: "mon-wed-fri", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hosts.ParseWeekdays(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got.String()); diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestEntry_Schedule(t *testing.T) {
+	t.Parallel()
+
+	e := hosts.ParseLine("0.0.0.0 google.com #freeblock:mon-fri@08:30-12 # 1.2.3.4")
+	s, err := e.Schedule(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hosts.Schedule{Windows: []hosts.Window{{
+		Days: 0b0111110, Start: 8*60 + 30, End: 12 * 60,
+	}}}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Error("unexpected schedule (-want +got):\n" + diff)
+	}
+	if got := s.Windows[0].String(); got != "mon-fri@08:30-12:00" {
+		t.Errorf("unexpected window %s", got)
+	}
+
+	s, err = hosts.ParseLine("0.0.0.0 google.com # 1.2.3.4").Schedule(nil)
+	if err != nil || len(s.Windows) != 0 {
+		t.Errorf("expected empty schedule, got %v %v", s, err)
+	}
+
+	e = hosts.ParseLine("0.0.0.0 google.com #freeblock:9to5")
+	e.Num = 3
+	_, err = e.Schedule(nil)
+	var as *hosts.ParseError
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *hosts.ParseError, got %v", err)
+	}
+	wantErr := `line 3: invalid directive "9to5": invalid time range "9to5"`
+	if diff := cmp.Diff(wantErr, err.Error()); diff != "" {
+		t.Error("unexpected error (-want +got):\n" + diff)
+	}
+
+	e = hosts.ParseLine("0.0.0.0 google.com #freeblock:weekdays@09-17")
+	_, err = e.Schedule(nil)
+	if !errors.As(err, &as) {
+		t.Fatalf("expected *hosts.ParseError, got %v", err)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	weekdays := hosts.Weekdays(0b0111110)
+	saturday := hosts.Weekdays(0b1000000)
+
+	tests := map[string]struct {
+		in      string
+		want    []hosts.Window
+		wantErr bool
+	}{
+		"one": {in: "09-17", want: []hosts.Window{{Start: 9 * 60, End: 17 * 60}}},
+		"two": {in: "09-12,13-17", want: []hosts.Window{
+			{Start: 9 * 60, End: 12 * 60},
+			{Start: 13 * 60, End: 17 * 60},
+		}},
+		"days_carry_over": {in: "mon-fri@09-12,13-17,sat@10-11", want: []hosts.Window{
+			{Days: weekdays, Start: 9 * 60, End: 12 * 60},
+			{Days: weekdays, Start: 13 * 60, End: 17 * 60},
+			{Days: saturday, Start: 10 * 60, End: 11 * 60},
+		}},
+		"empty_part": {in: "09-12,", wantErr: true},
+		"bad_part":   {in: "09-12,13", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hosts.ParseSchedule(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got.Windows); diff != "" {
+				t.Error("unexpected output (-want +got):\n" + diff)
+			}
+		})
+	}
+}
+
+func TestEntry_Schedule_merged(t *testing.T) {
+	t.Parallel()
+
+	e := hosts.ParseLine("0.0.0.0 reddit.com #freeblock:09-12 #freeblock:sat@10-11,13-17")
+	s, err := e.Schedule(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, w := range s.Windows {
+		got = append(got, w.String())
+	}
+	want := []string{"09:00-12:00", "sat@10:00-11:00", "sat@13:00-17:00"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("unexpected windows (-want +got):\n" + diff)
+	}
+
+	// October 9, 2021 was a Saturday.
+	w, ok := s.Active(time.Date(2021, 10, 9, 14, 0, 0, 0, time.UTC))
+	if !ok || w != s.Windows[2] {
+		t.Errorf("unexpected active window %v %v", w, ok)
+	}
+	_, ok = s.Active(time.Date(2021, 10, 9, 12, 30, 0, 0, time.UTC))
+	if ok {
+		t.Error("expected no active window during lunch")
+	}
+}
+
+func TestParseSchedule_timezone(t *testing.T) {
+	t.Parallel()
+
+	s, err := hosts.ParseSchedule("mon-fri@09-12,13-17@America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range s.Windows {
+		if w.Location == nil || w.Location.String() != "America/Denver" {
+			t.Errorf("unexpected location for %v", w)
+		}
+	}
+	if got := s.Windows[1].String(); got != "mon-fri@13:00-17:00@America/Denver" {
+		t.Errorf("unexpected window %s", got)
+	}
+
+	for _, bad := range []string{"09-17@Mars/Olympus_Mons", "09-17@", "09-17@mon"} {
+		if _, err = hosts.ParseSchedule(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWindow_Contains_dst(t *testing.T) {
+	t.Parallel()
+
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workHours := hosts.Window{Start: 9 * 60, End: 17 * 60, Location: denver}
+	night := hosts.Window{Start: 1 * 60, End: 2 * 60, Location: denver}
+	springNight := hosts.Window{Start: 2*60 + 30, End: 3 * 60, Location: denver}
+
+	// In 2021, Denver moved from MST (-7) to MDT (-6) at 02:00 on March 14, and back at 02:00 on
+	// November 7.
+	tests := []struct {
+		w    hosts.Window
+		t    time.Time
+		want bool
+	}{
+		// 08:30 MST, then 09:30 MDT at the same UTC time of day.
+		{workHours, time.Date(2021, 3, 12, 15, 30, 0, 0, time.UTC), false},
+		{workHours, time.Date(2021, 3, 15, 15, 30, 0, 0, time.UTC), true},
+		// 16:30 MDT, then 15:30 MST.
+		{workHours, time.Date(2021, 11, 5, 23, 30, 0, 0, time.UTC), false},
+		{workHours, time.Date(2021, 11, 8, 23, 30, 0, 0, time.UTC), true},
+		// 01:30 happens twice on November 7, and both are inside the window.
+		{night, time.Date(2021, 11, 7, 7, 30, 0, 0, time.UTC), true},
+		{night, time.Date(2021, 11, 7, 8, 30, 0, 0, time.UTC), true},
+		{night, time.Date(2021, 11, 7, 9, 30, 0, 0, time.UTC), false},
+		// 02:30 never happens on March 14. The clock goes from 01:59 MST to 03:00 MDT.
+		{springNight, time.Date(2021, 3, 14, 8, 59, 0, 0, time.UTC), false},
+		{springNight, time.Date(2021, 3, 14, 9, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tc := range tests {
+		if got := tc.w.Contains(tc.t); got != tc.want {
+			t.Errorf("%v.Contains(%v) = %v, want %v", tc.w, tc.t.In(denver), got, tc.want)
+		}
+	}
+}
+
+func TestFile_Schedules(t *testing.T) {
+	t.Parallel()
+
+	f := hosts.Parse([]hosts.Line{
+		"# Schedules",
+		"#freeblock:schedule workhours = mon-fri 09:00-17:30",
+		"  #freeblock:schedule\tlunch=12-13",
+		"#freeblock:schedule split = mon-fri 09-12, 13-17 America/Denver",
+		"#freeblock:schedules are defined above",
+		"0.0.0.0 reddit.com #freeblock:schedule=workhours #freeblock:sat@10-11",
+		"0.0.0.0 example.com #freeblock:schedule=weekends",
+	})
+
+	named, err := f.Schedules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for name, s := range named {
+		for _, w := range s.Windows {
+			got[name] = append(got[name], w.String())
+		}
+	}
+	want := map[string][]string{
+		"workhours": {"mon-fri@09:00-17:30"},
+		"lunch":     {"12:00-13:00"},
+		"split": {
+			"mon-fri@09:00-12:00@America/Denver",
+			"mon-fri@13:00-17:00@America/Denver",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("unexpected schedules (-want +got):\n" + diff)
+	}
+
+	s, err := f.Entries[5].Schedule(named)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Windows) != 2 || s.Windows[0] != named["workhours"].Windows[0] {
+		t.Errorf("unexpected schedule %v", s)
+	}
+
+	_, err = f.Entries[6].Schedule(named)
+	want2 := `line 7: invalid directive "schedule=weekends": unknown schedule "weekends"`
+	if err == nil || err.Error() != want2 {
+		t.Errorf("expected error %q, got %v", want2, err)
+	}
+}
+
+func TestFile_Schedules_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"no_equals": "#freeblock:schedule workhours 09-17",
+		"no_name":   "#freeblock:schedule = 09-17",
+		"bad_value": "#freeblock:schedule workhours = 9to5",
+		"duplicate": "#freeblock:schedule lunch = 12-13",
+	}
+
+	for name, line := range tests {
+		line := line
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := hosts.Parse([]hosts.Line{"#freeblock:schedule lunch = 12-13", hosts.Line(line)})
+			_, err := f.Schedules()
+			var as *hosts.ParseError
+			if !errors.As(err, &as) {
+				t.Fatalf("expected *hosts.ParseError, got %v", err)
+			}
+			if as.Line != 2 {
+				t.Errorf("expected error on line 2, got %v", as)
+			}
+		})
+	}
+}
+
+func TestFile_Exceptions(t *testing.T) {
+	t.Parallel()
+
+	f := hosts.Parse([]hosts.Line{
+		"#freeblock:except 2026-12-24..2026-12-26, 2027-01-01",
+		"#freeblock:vacation 2026-08-31",
+		"0.0.0.0 reddit.com #freeblock:09-17",
+	})
+
+	got, err := f.Exceptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	for _, r := range got {
+		strs = append(strs, r.String())
+	}
+	want := []string{"2026-12-24..2026-12-26", "2027-01-01", "..2026-08-31"}
+	if diff := cmp.Diff(want, strs); diff != "" {
+		t.Error("unexpected exceptions (-want +got):\n" + diff)
+	}
+
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2026, 8, 31, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 12, 23, 23, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 12, 26, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 12, 27, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC), true},
+	}
+	for _, tc := range tests {
+		var in bool
+		for _, r := range got {
+			in = in || r.Contains(tc.t)
+		}
+		if in != tc.want {
+			t.Errorf("expected %v to be excepted: %v", tc.t, tc.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"#freeblock:except 2026-12-26..2026-12-24",
+		"#freeblock:except 2026-13-01",
+		"#freeblock:except 2026-12-24..2026-12-25..2026-12-26",
+		"#freeblock:vacation tomorrow",
+	} {
+		_, err = hosts.Parse([]hosts.Line{hosts.Line(bad)}).Exceptions()
+		var as *hosts.ParseError
+		if !errors.As(err, &as) {
+			t.Errorf("expected *hosts.ParseError for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestFile_SetVacation(t *testing.T) {
+	t.Parallel()
+
+	last := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	f := hosts.Parse([]hosts.Line{"127.0.0.1 localhost"})
+	f.SetVacation(last)
+	want := []hosts.Line{"#freeblock:vacation 2026-12-31", "127.0.0.1 localhost"}
+	if diff := cmp.Diff(want, f.Lines()); diff != "" {
+		t.Error("unexpected lines (-want +got):\n" + diff)
+	}
+
+	f = hosts.Parse([]hosts.Line{
+		"127.0.0.1 localhost",
+		"#freeblock:vacation 2026-08-31",
+		"#freeblock:vacation 2026-09-30",
+	})
+	f.SetVacation(last)
+	want = []hosts.Line{"127.0.0.1 localhost", "#freeblock:vacation 2026-12-31"}
+	if diff := cmp.Diff(want, f.Lines()); diff != "" {
+		t.Error("unexpected lines (-want +got):\n" + diff)
+	}
+
+	if !f.ClearVacation() {
+		t.Error("expected a vacation to be cleared")
+	}
+	if f.ClearVacation() {
+		t.Error("expected no vacation to be cleared")
+	}
+	want = []hosts.Line{"127.0.0.1 localhost"}
+	if diff := cmp.Diff(want, f.Lines()); diff != "" {
+		t.Error("unexpected lines (-want +got):\n" + diff)
+	}
+}
+
+func TestSchedule_NextChange(t *testing.T) {
+	t.Parallel()
+
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(directive string) hosts.Schedule {
+		s, err := hosts.ParseSchedule(directive)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	// October 4, 2021 was a Monday.
+	tests := map[string]struct {
+		s    hosts.Schedule
+		t    time.Time
+		want time.Time
+	}{
+		"before_start": {
+			parse("09-17"), time.Date(2021, 10, 4, 8, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC),
+		},
+		"at_start": {
+			parse("09-17"), time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 4, 17, 0, 0, 0, time.UTC),
+		},
+		"after_end": {
+			parse("09-17"), time.Date(2021, 10, 4, 17, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 5, 9, 0, 0, 0, time.UTC),
+		},
+		"weekend": {
+			parse("mon-fri@09-17"), time.Date(2021, 10, 8, 18, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 11, 9, 0, 0, 0, time.UTC),
+		},
+		"overnight_end": {
+			parse("fri@22-06"), time.Date(2021, 10, 9, 1, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 9, 6, 0, 0, 0, time.UTC),
+		},
+		"overnight_next_week": {
+			parse("fri@22-06"), time.Date(2021, 10, 9, 6, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 15, 22, 0, 0, 0, time.UTC),
+		},
+		"several": {
+			parse("09-12,13-17"), time.Date(2021, 10, 4, 12, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 4, 13, 0, 0, 0, time.UTC),
+		},
+		"end_of_day": {
+			parse("22-24"), time.Date(2021, 10, 4, 23, 0, 0, 0, time.UTC),
+			time.Date(2021, 10, 5, 0, 0, 0, 0, time.UTC),
+		},
+		"zone": {
+			// This is 17:00 in Denver, the evening before daylight saving time starts.
+			parse("09-17@America/Denver"), time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 14, 9, 0, 0, 0, denver),
+		},
+		"empty":      {parse("09-09"), time.Date(2021, 10, 4, 8, 0, 0, 0, time.UTC), time.Time{}},
+		"no_windows": {hosts.Schedule{}, time.Date(2021, 10, 4, 8, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := tc.s.NextChange(tc.t)
+			if ok != (!tc.want.IsZero()) || !got.Equal(tc.want) {
+				t.Errorf("got %v %v, want %v", got, ok, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package services provides lists of the domains used by popular websites, so that a whole service
+// can be blocked at once.
+package services
+
+import (
+	"bufio"
+	_ "embed" // for the dataset
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//go:embed services.txt
+var dataset string
+
+// Services maps the name of each service to the domains it uses.
+type Services map[string][]string
+
+// Default returns the built-in list of services.
+func Default() Services {
+	s := make(Services)
+	if err := s.Read(strings.NewReader(dataset)); err != nil {
+		panic(fmt.Sprintf("invalid built-in services: %v", err))
+	}
+
+	return s
+}
+
+// Read reads services from r into s. Each line of r gives the name of a service followed by its
+// domains, separated by whitespace. A line replaces any service with the same name already in s,
+// unless the name starts with '+', in which case the domains are added to the service. Blank lines
+// and lines starting with '#' are ignored.
+func (s Services) Read(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+
+	for num := 1; sc.Scan(); num++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		name := strings.TrimPrefix(fields[0], "+")
+		switch {
+		case name == "":
+			return &ParseError{num, errors.New("missing service name")}
+		case len(fields) == 1:
+			return &ParseError{num, fmt.Errorf("service %q has no domains", name)}
+		}
+
+		if name != fields[0] {
+			s[name] = append(s[name], fields[1:]...)
+		} else {
+			s[name] = fields[1:]
+		}
+	}
+
+	return sc.Err()
+}
+
+// Names returns the names of the services in s in alphabetical order.
+func (s Services) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseError is returned when a line of a services file can't be parsed.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
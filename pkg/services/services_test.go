@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/kylrth/freeblock/pkg/services"
+)
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	s := services.Default()
+
+	for _, name := range []string{"reddit", "youtube"} {
+		if len(s[name]) < 2 {
+			t.Errorf("expected several domains for %s, got %v", name, s[name])
+		}
+	}
+	if s["reddit"][0] != "reddit.com" {
+		t.Errorf("expected reddit.com first, got %v", s["reddit"])
+	}
+}
+
+func TestServices_Read(t *testing.T) {
+	t.Parallel()
+
+	s := services.Services{
+		"reddit":  {"reddit.com", "redd.it"},
+		"youtube": {"youtube.com", "ytimg.com"},
+	}
+
+	err := s.Read(strings.NewReader(`# my changes
+
++reddit   old.reddit.com
+youtube	youtube.com
+mysite a.example.com b.example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := services.Services{
+		"reddit":  {"reddit.com", "redd.it", "old.reddit.com"},
+		"youtube": {"youtube.com"},
+		"mysite":  {"a.example.com", "b.example.com"},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Error("unexpected services (-want +got):\n" + diff)
+	}
+
+	if diff := cmp.Diff([]string{"mysite", "reddit", "youtube"}, s.Names()); diff != "" {
+		t.Error("unexpected names (-want +got):\n" + diff)
+	}
+}
+
+func TestServices_Read_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in   string
+		line int
+	}{
+		"no_domains": {"reddit reddit.com\nyoutube\n", 2},
+		"no_name":    {"\n\n+ reddit.com\n", 3},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := make(services.Services).Read(strings.NewReader(tc.in))
+
+			var parseErr *services.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected *services.ParseError, got %v", err)
+			}
+			if parseErr.Line != tc.line {
+				t.Errorf("expected line %d, got %d", tc.line, parseErr.Line)
+			}
+		})
+	}
+}